@@ -0,0 +1,143 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/consulApi"
+)
+
+// consulRetryInterval is how often Consul is tried again while running from the cached configuration
+var consulRetryInterval = time.Second * 10
+
+// cachedConfiguration is the last configuration applied from Consul, as saved in Options.CacheFile
+type cachedConfiguration struct {
+	Key         string                 `json:"key"`
+	Tree        bool                   `json:"tree"`
+	ModifyIndex uint64                 `json:"modifyIndex"`
+	Document    map[string]interface{} `json:"document"`
+}
+
+// saveCache writes the document as written to the cache file when it was read from Consul. The file is
+// replaced as a whole so a crash part way through never leaves a partial cache. Errors are only logged since
+// the configuration has already been applied. Callers must hold updateMutex.
+func (config *Configuration) saveCache(document map[string]interface{}, modifyIndex uint64) {
+	if config.cachePath == "" || modifyIndex == 0 {
+		return
+	}
+
+	cached := cachedConfiguration{Key: config.consulKey, Tree: config.consulTree, ModifyIndex: modifyIndex, Document: document}
+	cacheJson, err := json.MarshalIndent(cached, "", "  ")
+	if err != nil {
+		log.Printf("Unable to cache configuration: %s", err.Error())
+		return
+	}
+
+	if err := writeFileAtomically(config.cachePath, cacheJson); err != nil {
+		log.Printf("Unable to cache configuration in %s: %s", config.cachePath, err.Error())
+	}
+}
+
+// readCache returns the configuration cached for the Consul key, as a KeyValuePair without a ModifyIndex so
+// whatever is read from Consul later replaces it
+func readCache(path string, key string, tree bool) (*consulApi.KeyValuePair, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no cache file set")
+	}
+
+	cacheJson, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cached cachedConfiguration
+	if err := json.Unmarshal(cacheJson, &cached); err != nil {
+		return nil, fmt.Errorf("unable to parse cached configuration in %s: %s", path, err.Error())
+	}
+
+	if cached.Key != key || cached.Tree != tree || cached.Document == nil {
+		return nil, fmt.Errorf("configuration cached in %s is not for Consul key '%s'", path, key)
+	}
+
+	documentJson, err := json.Marshal(cached.Document)
+	if err != nil {
+		return nil, err
+	}
+
+	return &consulApi.KeyValuePair{Key: key, Value: documentJson}, nil
+}
+
+// writeFileAtomically writes data to a temporary file next to path and renames it over path
+func writeFileAtomically(path string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+
+	return nil
+}
+
+// reconcileWithConsul tries Consul until it is reachable, then starts watching it and applies the configuration
+// in Consul over the cached one, notifying the callbacks of the differences
+func (config *Configuration) reconcileWithConsul(ctx context.Context, layers []string, consul *consulApi.Client, consulConfigKey string, tree bool) {
+	defer config.background.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(consulRetryInterval):
+		}
+
+		var err error
+		if tree {
+			_, err = checkAndUpdateTreeFromLocal(consul, consulConfigKey, layers)
+		} else {
+			_, err = checkAndUpdateFromLocal(consul, consulConfigKey, layers)
+		}
+		if err != nil {
+			continue
+		}
+
+		// Watch first so no change is missed between reading the configuration and the watch starting
+		if err := config.watchConsul(ctx, consul, consulConfigKey, tree); err != nil {
+			log.Print(err.Error())
+			continue
+		}
+
+		log.Printf("Consul Service reachable again, replacing cached configuration with %s from Consul Service", consulConfigKey)
+		if err := config.refreshFromConsul(); err != nil {
+			log.Printf("Unable to apply configuration from Consul Service: %s", err.Error())
+		}
+		return
+	}
+}
@@ -0,0 +1,153 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newUnreachableConsul returns the address of a proxy to the mock Consul service that fails every request
+// until reachable is set to 1
+func newUnreachableConsul(t *testing.T, reachable *int32) (string, func()) {
+	target, err := url.Parse(consulUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target.Path = ""
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if atomic.LoadInt32(reachable) == 0 {
+			http.Error(writer, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		proxy.ServeHTTP(writer, request)
+	}))
+
+	return server.URL + "/v1/kv", server.Close
+}
+
+func TestCacheFallbackAndReconcile(t *testing.T) {
+	directory, err := ioutil.TempDir("", "configurationCache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	cachePath := filepath.Join(directory, "consul-cache.json")
+	appConfigKey := "config/unit-test-cache-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	options := Options{
+		SectionName:   "inventory-service",
+		FilePaths:     []string{"./testData/layered/base.json"},
+		ConsulAddress: consulUrl,
+		ConsulKey:     appConfigKey,
+		CacheFile:     cachePath,
+	}
+
+	// A successful start caches the configuration
+	first, err := NewConfigurationWithOptions(options)
+	if err != nil {
+		t.Fatalf("NewConfigurationWithOptions returned error %s", err.Error())
+	}
+	if err := first.Set("port", "8085"); err != nil {
+		t.Fatalf("Set returned error %s", err.Error())
+	}
+	first.Close()
+
+	if _, err := readCache(cachePath, appConfigKey, false); err != nil {
+		t.Fatalf("Expected configuration to be cached, got %s", err.Error())
+	}
+
+	// Consul is changed while the next instance can't reach it
+	if err := consul.PutValue(appConfigKey, `{"port": "9090", "inventory-service": {"serviceName": "Offline Inventory Service"}}`); err != nil {
+		t.Fatalf("PutValue returned error %s", err.Error())
+	}
+
+	defer func(interval time.Duration) { consulRetryInterval = interval }(consulRetryInterval)
+	consulRetryInterval = time.Millisecond * 100
+
+	var reachable int32
+	unreachableUrl, closeProxy := newUnreachableConsul(t, &reachable)
+	defer closeProxy()
+
+	options.ConsulAddress = unreachableUrl
+	target, err := NewConfigurationWithOptions(options)
+	if err != nil {
+		t.Fatalf("Expected cached configuration to be used, got error %s", err.Error())
+	}
+	defer target.Close()
+
+	if actual, _ := target.GetString("port"); actual != "8085" {
+		t.Errorf("Cached port not as expected. Expected='8085', Actual='%s'", actual)
+	}
+
+	changes := make(chan []ChangeDetails, 10)
+	target.SetConfigChangeCallback(func(changedList []ChangeDetails) {
+		changes <- changedList
+	})
+
+	atomic.StoreInt32(&reachable, 1)
+
+	select {
+	case changedList := <-changes:
+		expected := ChangeDetails{Name: "port", Value: "9090", OldValue: "8085", Operation: Updated}
+		if len(changedList) != 8 || changedList[4] != expected {
+			t.Errorf("Expected changes from the cached configuration, got %v", changedList)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for configuration from Consul")
+	}
+
+	if actual, _ := target.GetString("port"); actual != "9090" {
+		t.Errorf("Reconciled port not as expected. Expected='9090', Actual='%s'", actual)
+	}
+
+	// Changes are watched once reconciled
+	if err := consul.PutValue(appConfigKey, `{"port": "9091"}`); err != nil {
+		t.Fatalf("PutValue returned error %s", err.Error())
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for watched change")
+	}
+}
+
+func TestCacheNotUsable(t *testing.T) {
+	var reachable int32
+	unreachableUrl, closeProxy := newUnreachableConsul(t, &reachable)
+	defer closeProxy()
+
+	cachePath := filepath.Join(os.TempDir(), "unit-test-cache-"+strconv.FormatInt(time.Now().UnixNano(), 10)+".json")
+	defer os.Remove(cachePath)
+
+	if err := ioutil.WriteFile(cachePath, []byte(`{"key": "config/other", "document": {"port": "8080"}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"", cachePath, cachePath + ".missing"} {
+		_, err := NewConfigurationWithOptions(Options{
+			ConsulAddress: unreachableUrl,
+			ConsulKey:     "config/unit-test-cache",
+			CacheFile:     path,
+		})
+		if err == nil {
+			t.Errorf("Expected an error when Consul is unreachable and cache '%s' can't be used", path)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"reflect"
+	"sort"
+)
+
+// appendObjectChanges compares two JSON objects key by key, descending into nested objects, and appends
+// a ChangeDetails for every leaf value that was added, updated or deleted beneath prefix.
+func appendObjectChanges(changedList []ChangeDetails, prefix string, previousObject map[string]interface{}, newObject map[string]interface{}) []ChangeDetails {
+	for _, name := range sortedKeys(previousObject, newObject) {
+		path := joinPath(prefix, name)
+		previousValue := previousObject[name]
+		newValue := newObject[name]
+
+		switch {
+		case previousValue == nil && newValue == nil:
+			continue
+		case newValue == nil:
+			changedList = appendLeafChanges(changedList, path, previousValue, Deleted)
+		case previousValue == nil:
+			changedList = appendLeafChanges(changedList, path, newValue, Added)
+		default:
+			changedList = appendValueChanges(changedList, path, previousValue, newValue)
+		}
+	}
+
+	return changedList
+}
+
+func appendValueChanges(changedList []ChangeDetails, path string, previousValue interface{}, newValue interface{}) []ChangeDetails {
+	previousObject, previousIsObject := previousValue.(map[string]interface{})
+	newObject, newIsObject := newValue.(map[string]interface{})
+	previousArray, previousIsArray := previousValue.([]interface{})
+	newArray, newIsArray := newValue.([]interface{})
+
+	switch {
+	case previousIsObject && newIsObject:
+		return appendObjectChanges(changedList, path, previousObject, newObject)
+
+	case previousIsArray && newIsArray && len(previousArray) == len(newArray):
+		// Elements are compared in place so a change is named by its index. Arrays that grew or shrank are
+		// reported as a whole below, since elements may have moved.
+		for index := range newArray {
+			changedList = appendValueChanges(changedList, indexPath(path, index), previousArray[index], newArray[index])
+		}
+		return changedList
+
+	case previousIsObject != newIsObject:
+		// An object replaced by a plain value, or the reverse, removes every old leaf and adds every new one.
+		changedList = appendLeafChanges(changedList, path, previousValue, Deleted)
+		return appendLeafChanges(changedList, path, newValue, Added)
+
+	case !reflect.DeepEqual(previousValue, newValue):
+		return append(changedList, ChangeDetails{
+			Name:      path,
+			Value:     newValue,
+			OldValue:  previousValue,
+			Operation: Updated,
+		})
+	}
+
+	return changedList
+}
+
+// appendLeafChanges appends an Added or Deleted ChangeDetails for each leaf beneath path. Empty objects are treated as leaves.
+func appendLeafChanges(changedList []ChangeDetails, path string, value interface{}, operation ChangeType) []ChangeDetails {
+	if object, ok := value.(map[string]interface{}); ok && len(object) > 0 {
+		for _, name := range sortedKeys(object) {
+			changedList = appendLeafChanges(changedList, joinPath(path, name), object[name], operation)
+		}
+		return changedList
+	}
+
+	details := ChangeDetails{
+		Name:      path,
+		Operation: operation,
+	}
+
+	if operation == Deleted {
+		details.OldValue = value
+	} else {
+		details.Value = value
+	}
+
+	return append(changedList, details)
+}
+
+// sortedKeys returns the union of the keys of the objects in sorted order so changes are reported consistently
+func sortedKeys(objects ...map[string]interface{}) []string {
+	keySet := make(map[string]bool)
+	for _, object := range objects {
+		for key := range object {
+			keySet[key] = true
+		}
+	}
+
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
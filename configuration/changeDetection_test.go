@@ -0,0 +1,97 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func parseTestJson(jsonText string, t *testing.T) map[string]interface{} {
+	var parsedJson map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonText), &parsedJson); err != nil {
+		t.Fatalf("unable to parse test JSON: %s", err.Error())
+	}
+	return parsedJson
+}
+
+func TestAppendObjectChangesNested(t *testing.T) {
+	previous := parseTestJson(`{"influx": {"url": "a", "database": "inventory", "pool": {"size": 5}}, "list": [1, 2], "port": "8080"}`, t)
+	current := parseTestJson(`{"influx": {"url": "b", "pool": {"size": 5, "idle": 2}, "retries": 3}, "list": [1, 2, 3], "port": "8080"}`, t)
+
+	expected := []ChangeDetails{
+		{Name: "svc.influx.database", OldValue: "inventory", Operation: Deleted},
+		{Name: "svc.influx.pool.idle", Value: float64(2), Operation: Added},
+		{Name: "svc.influx.retries", Value: float64(3), Operation: Added},
+		{Name: "svc.influx.url", Value: "b", OldValue: "a", Operation: Updated},
+		{Name: "svc.list", Value: []interface{}{float64(1), float64(2), float64(3)}, OldValue: []interface{}{float64(1), float64(2)}, Operation: Updated},
+	}
+
+	actual := appendObjectChanges(nil, "svc", previous, current)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("changes not as expected.\nExpected='%+v'\nActual='%+v'", expected, actual)
+	}
+}
+
+func TestAppendObjectChangesTypeChanged(t *testing.T) {
+	previous := parseTestJson(`{"influx": {"url": "a", "database": "inventory"}, "port": 8080}`, t)
+	current := parseTestJson(`{"influx": "http://influx:8086", "port": {"http": 8080}}`, t)
+
+	expected := []ChangeDetails{
+		{Name: "influx.database", OldValue: "inventory", Operation: Deleted},
+		{Name: "influx.url", OldValue: "a", Operation: Deleted},
+		{Name: "influx", Value: "http://influx:8086", Operation: Added},
+		{Name: "port", OldValue: float64(8080), Operation: Deleted},
+		{Name: "port.http", Value: float64(8080), Operation: Added},
+	}
+
+	actual := appendObjectChanges(nil, "", previous, current)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("changes not as expected.\nExpected='%+v'\nActual='%+v'", expected, actual)
+	}
+}
+
+func TestAppendObjectChangesNoChanges(t *testing.T) {
+	previous := parseTestJson(`{"influx": {"url": "a", "tags": ["x", "y"]}, "list": [1, 2]}`, t)
+	current := parseTestJson(`{"influx": {"url": "a", "tags": ["x", "y"]}, "list": [1, 2]}`, t)
+
+	actual := appendObjectChanges(nil, "", previous, current)
+
+	if len(actual) != 0 {
+		t.Errorf("expected no changes and got %+v", actual)
+	}
+}
+
+func TestGlobalNestedChangesNotified(t *testing.T) {
+	target := &Configuration{sectionName: "inventory-service"}
+	target.processConfigurationChanged([]byte(`{"port": "8080", "influx": {"url": "a"}, "inventory-service": {"port": "9090"}, "item-finder-service": {"port": "7070"}}`))
+
+	var actualChanges []ChangeDetails
+	target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+		actualChanges = changes
+	})
+
+	var subscribed interface{}
+	target.Subscribe("influx.*", func(oldValue interface{}, newValue interface{}) {
+		subscribed = newValue
+	})
+
+	// The change to another service's section is not notified
+	target.processConfigurationChanged([]byte(`{"port": "8080", "influx": {"url": "b"}, "inventory-service": {"port": "9090"}, "item-finder-service": {"port": "7071"}}`))
+
+	expected := []ChangeDetails{{Name: "influx.url", Value: "b", OldValue: "a", Operation: Updated}}
+	if !reflect.DeepEqual(expected, actualChanges) {
+		t.Errorf("changes not as expected.\nExpected='%+v'\nActual='%+v'", expected, actualChanges)
+	}
+
+	if subscribed != "b" {
+		t.Errorf("Subscription to influx.* not called with the new value, got %v", subscribed)
+	}
+}
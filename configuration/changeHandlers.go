@@ -0,0 +1,142 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/go-metrics"
+)
+
+// changesRejectedMetric counts the updates rolled back because a change handler returned an error
+const changesRejectedMetric = "Configuration.ChangesRejected"
+
+// ChangeRejectedError is returned when a change handler returned an error for an update, which was rolled back
+type ChangeRejectedError struct {
+	Changes []ChangeDetails
+	Err     error
+}
+
+func (rejectedError *ChangeRejectedError) Error() string {
+	return fmt.Sprintf("configuration change rejected by change handler: %s", rejectedError.Err.Error())
+}
+
+type changeHandler struct {
+	id      uint64
+	handler func([]ChangeDetails) error
+}
+
+// AddChangeHandler registers a handler that is called with the changes of every update before the change
+// callback and the subscriptions, in the order the handlers were added. The update isn't applied until every
+// handler accepts it, so the getters still return the previous values while the handlers run and the new
+// values are only in the changes. A handler returning an error rejects the update: the handlers called before
+// it are called again with the reverse of the changes so they can undo them, and the error is passed to the
+// validation error callback. Set, Delete and Rollback return an error while the handlers run, so a handler
+// can't change the configuration itself. The returned function removes the handler.
+func (config *Configuration) AddChangeHandler(handler func([]ChangeDetails) error) func() {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	config.nextSubscriptionId++
+	id := config.nextSubscriptionId
+
+	config.changeHandlers = append(config.changeHandlers, &changeHandler{id: id, handler: handler})
+
+	return func() {
+		config.removeChangeHandler(id)
+	}
+}
+
+func (config *Configuration) removeChangeHandler(id uint64) {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	for index, existing := range config.changeHandlers {
+		if existing.id == id {
+			// Copy rather than modify in place since callChangeHandlers may be iterating the current slice
+			changeHandlers := make([]*changeHandler, 0, len(config.changeHandlers)-1)
+			changeHandlers = append(changeHandlers, config.changeHandlers[:index]...)
+			config.changeHandlers = append(changeHandlers, config.changeHandlers[index+1:]...)
+			return
+		}
+	}
+}
+
+// callChangeHandlers passes the changes to every change handler until one returns an error, in which case the
+// handlers already called are passed the compensating changes and a *ChangeRejectedError is returned
+func (config *Configuration) callChangeHandlers(changedList []ChangeDetails) error {
+	changeHandlers := config.startHandlingChanges()
+	defer config.stopHandlingChanges()
+
+	for index, current := range changeHandlers {
+		err := current.handler(changedList)
+		if err == nil {
+			continue
+		}
+
+		metrics.GetOrRegisterCounter(changesRejectedMetric, nil).Inc(1)
+		compensate(changeHandlers[:index], changedList)
+
+		return &ChangeRejectedError{Changes: changedList, Err: err}
+	}
+
+	return nil
+}
+
+// undoChangeHandlers passes the compensating changes to every change handler, as when changes the handlers
+// accepted could not be written
+func (config *Configuration) undoChangeHandlers(changedList []ChangeDetails) {
+	changeHandlers := config.startHandlingChanges()
+	defer config.stopHandlingChanges()
+
+	compensate(changeHandlers, changedList)
+}
+
+// startHandlingChanges returns the change handlers to call and marks them as running until
+// stopHandlingChanges is called
+func (config *Configuration) startHandlingChanges() []*changeHandler {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	config.handlingChanges = true
+	return config.changeHandlers
+}
+
+func (config *Configuration) stopHandlingChanges() {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	config.handlingChanges = false
+}
+
+func compensate(changeHandlers []*changeHandler, changedList []ChangeDetails) {
+	compensatingList := reverseChanges(changedList)
+	for _, current := range changeHandlers {
+		// A rollback can't itself be rejected, so all that can be done is to report it
+		if err := current.handler(compensatingList); err != nil {
+			log.Printf("change handler failed to undo rejected configuration change: %s", err.Error())
+		}
+	}
+}
+
+// reverseChanges returns the changes that undo changedList
+func reverseChanges(changedList []ChangeDetails) []ChangeDetails {
+	reversedList := make([]ChangeDetails, 0, len(changedList))
+	for _, change := range changedList {
+		reversed := ChangeDetails{Name: change.Name, Value: change.OldValue, OldValue: change.Value, Operation: change.Operation}
+		switch change.Operation {
+		case Added:
+			reversed.Operation = Deleted
+		case Deleted:
+			reversed.Operation = Added
+		}
+		reversedList = append(reversedList, reversed)
+	}
+
+	return reversedList
+}
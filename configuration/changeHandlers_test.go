@@ -0,0 +1,169 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/go-metrics"
+)
+
+func TestChangeHandlerReject(t *testing.T) {
+	target := &Configuration{}
+	target.processConfigurationChanged([]byte(`{"poolSize": 10, "loggingLevel": "info"}`))
+
+	var firstChanges [][]ChangeDetails
+	target.AddChangeHandler(func(changes []ChangeDetails) error {
+		firstChanges = append(firstChanges, changes)
+		return nil
+	})
+
+	target.AddChangeHandler(func(changes []ChangeDetails) error {
+		// The update isn't visible to the getters until it is accepted
+		if poolSize, _ := target.GetInt("poolSize"); poolSize != 10 {
+			return fmt.Errorf("unexpected pool size %d while handling changes", poolSize)
+		}
+
+		for _, change := range changes {
+			if change.Name == "poolSize" && change.Value.(float64) <= 0 {
+				return errors.New("pool size must be positive")
+			}
+		}
+		return nil
+	})
+
+	var lastHandlerCalled, callbackCalled bool
+	target.AddChangeHandler(func(changes []ChangeDetails) error {
+		lastHandlerCalled = true
+		return nil
+	})
+	target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+		callbackCalled = true
+	})
+
+	var rejected error
+	target.SetValidationErrorCallback(func(err error) {
+		rejected = err
+	})
+
+	counter := metrics.GetOrRegisterCounter(changesRejectedMetric, nil)
+	rejectedBefore := counter.Count()
+
+	target.processConfigurationChanged([]byte(`{"poolSize": -1, "loggingLevel": "debug"}`))
+
+	if _, ok := rejected.(*ChangeRejectedError); !ok {
+		t.Fatalf("Expected a *ChangeRejectedError, got %v", rejected)
+	}
+
+	if counter.Count() != rejectedBefore+1 {
+		t.Errorf("Expected the rejection to be counted. Expected='%d', Actual='%d'", rejectedBefore+1, counter.Count())
+	}
+
+	if actual, _ := target.GetInt("poolSize"); actual != 10 {
+		t.Errorf("poolSize not restored. Expected='10', Actual='%d'", actual)
+	}
+
+	if actual, _ := target.GetString("loggingLevel"); actual != "info" {
+		t.Errorf("loggingLevel not restored. Expected='info', Actual='%s'", actual)
+	}
+
+	if lastHandlerCalled || callbackCalled {
+		t.Error("Expected the callbacks after the rejecting handler not to be called")
+	}
+
+	expected := [][]ChangeDetails{
+		{
+			{Name: "loggingLevel", Value: "debug", OldValue: "info", Operation: Updated},
+			{Name: "poolSize", Value: float64(-1), OldValue: float64(10), Operation: Updated},
+		},
+		{
+			{Name: "loggingLevel", Value: "info", OldValue: "debug", Operation: Updated},
+			{Name: "poolSize", Value: float64(10), OldValue: float64(-1), Operation: Updated},
+		},
+	}
+	if !reflect.DeepEqual(expected, firstChanges) {
+		t.Errorf("Changes and compensating changes not as expected.\nExpected='%v'\nActual='%v'", expected, firstChanges)
+	}
+
+	// A change the handlers accept is applied as usual
+	target.processConfigurationChanged([]byte(`{"poolSize": 20, "loggingLevel": "info"}`))
+
+	if actual, _ := target.GetInt("poolSize"); actual != 20 {
+		t.Errorf("poolSize not as expected. Expected='20', Actual='%d'", actual)
+	}
+
+	if !lastHandlerCalled || !callbackCalled {
+		t.Error("Expected every callback to be called for an accepted change")
+	}
+}
+
+func TestReverseChanges(t *testing.T) {
+	changes := []ChangeDetails{
+		{Name: "added", Value: "a", Operation: Added},
+		{Name: "deleted", OldValue: "d", Operation: Deleted},
+	}
+
+	expected := []ChangeDetails{
+		{Name: "added", OldValue: "a", Operation: Deleted},
+		{Name: "deleted", Value: "d", Operation: Added},
+	}
+
+	if actual := reverseChanges(changes); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Reversed changes not as expected.\nExpected='%v'\nActual='%v'", expected, actual)
+	}
+}
+
+func TestSetRejectedByChangeHandler(t *testing.T) {
+	target, consul, key := newWriteBackConfig(t, false)
+
+	reject := true
+	calls := 0
+	target.AddChangeHandler(func(changes []ChangeDetails) error {
+		calls++
+		if reject {
+			return errors.New("port can't be changed while running")
+		}
+		return nil
+	})
+
+	if _, ok := target.Set("port", "8085").(*ChangeRejectedError); !ok {
+		t.Error("Expected a *ChangeRejectedError")
+	}
+
+	if actual, _ := target.GetString("port"); actual != "8080" {
+		t.Errorf("port not as expected. Expected='8080', Actual='%s'", actual)
+	}
+
+	if history := target.History(); len(history) != 1 {
+		t.Errorf("Expected the rejected version not to be in the history, got %d versions", len(history))
+	}
+
+	// The rejected change is never written to Consul
+	keyValuePair, err := consul.GetValue(key, nil)
+	if err != nil {
+		t.Fatalf("unable to read configuration from Consul: %s", err.Error())
+	}
+	if keyValuePair.ModifyIndex != target.History()[0].ModifyIndex {
+		t.Errorf("Expected the rejected change not to be written, Consul has %s", string(keyValuePair.Value))
+	}
+
+	// The handlers aren't called again when the accepted change is read back
+	reject = false
+	calls = 0
+	if err := target.Set("port", "8085"); err != nil {
+		t.Fatalf("Set returned error %s", err.Error())
+	}
+	if actual, _ := target.GetString("port"); actual != "8085" {
+		t.Errorf("port not as expected. Expected='8085', Actual='%s'", actual)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the change handler to be called once, called %d times", calls)
+	}
+}
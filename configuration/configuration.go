@@ -7,26 +7,98 @@
 package configuration
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
-	"os"
 	"path"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/consulApi"
-
-	"encoding/json"
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/gojsonschema"
 )
 
 type Configuration struct {
-	isNestedConfig       bool
-	parsedJson           map[string]interface{}
+	// current holds the *configSnapshot readers use. It is replaced as a whole, never modified in place,
+	// so readers always see a complete document even while an update is being applied.
+	current              atomic.Value
 	sectionName          string
+	mutex                sync.RWMutex
+	updateMutex          sync.Mutex
 	configChangeCallback func([]ChangeDetails)
+	subscriptions        []*subscription
+	nextSubscriptionId   uint64
+
+	schema                  *gojsonschema.Schema
+	validationErrorCallback func(error)
+
+	// defaults is a document of default values. It is replaced as a whole by SetDefaults.
+	defaults map[string]interface{}
+
+	// secrets caches the secrets that values in the document refer to
+	secrets secretCache
+
+	// profile is the name of the active block of the document's profiles
+	profile string
+
+	// envPrefix names the environment variables overriding keys, which are only read when it is set
+	envPrefix string
+
+	// consul, consulKey and consulTree are where Set and Delete write back to. consul is nil when the
+	// configuration is not read from Consul.
+	consul     *consulApi.Client
+	consulKey  string
+	consulTree bool
+
+	// flags are the command line flags bound to key paths by BindFlags and BindFlag, guarded by mutex
+	flags map[string]boundFlag
+
+	// rejectedIndex is the ModifyIndex of the last update rejected, and rejectedErr why, guarded by updateMutex
+	rejectedIndex uint64
+	rejectedErr   error
+
+	// changeHandlers are called with every update before the change callback and may reject it.
+	// handlingChanges is set while they run. Both are guarded by mutex.
+	changeHandlers  []*changeHandler
+	handlingChanges bool
+
+	// notifications are the callbacks queued by updates, which are delivered in order once updateMutex is
+	// released so a callback can change the configuration itself. notifying is set while they are delivered.
+	// Both are guarded by notifyMutex.
+	notifyMutex   sync.Mutex
+	notifications []func()
+	notifying     bool
+
+	// consulWatcher and fileWatcher watch the sources for changes until Close stops them. Either may be nil.
+	// consulWatcher is guarded by mutex since it is started in the background when running from the cache.
+	consulWatcher *Watcher
+	fileWatcher   *FileWatcher
+
+	// cancel stops the goroutines in background, which Close waits for
+	cancel     context.CancelFunc
+	background sync.WaitGroup
+
+	// cachePath is the file the last configuration read from Consul is saved to
+	cachePath string
+
+	// history is the bounded list of versions read from Consul, oldest first, guarded by mutex
+	history     []Version
+	historySize int
+}
+
+type configSnapshot struct {
+	parsedJson map[string]interface{}
+	// document is parsedJson as it was written, before references were expanded
+	document map[string]interface{}
+	// modifyIndex is the Consul ModifyIndex of the document, or 0 when it was not read from Consul
+	modifyIndex uint64
 }
 
 type ChangeType uint
@@ -38,72 +110,120 @@ const (
 	Deleted
 )
 
+// ChangeDetails describes a single leaf value that changed. Name is the full path to the value in the syntax
+// the getters take, such as "readers[2].host", or a JSON Pointer when a key contains '.', '[' or ']'.
+// Value is the new value and OldValue the previous one, which are nil for Deleted and Added respectively.
 type ChangeDetails struct {
 	Name      string
 	Value     interface{}
+	OldValue  interface{}
 	Operation ChangeType
 }
 
 func NewSectionedConfiguration(sectionName string) (*Configuration, error) {
-	config := Configuration{}
-	config.sectionName = sectionName
+	_, callerPath, _, _ := runtime.Caller(1)
 
-	err := config.loadConfiguration()
-	if err != nil {
-		return nil, err
-	}
+	options := optionsFromEnvironment(callerPath)
+	options.SectionName = sectionName
 
-	return &config, nil
+	return NewConfigurationWithOptions(options)
 }
 
 func NewConfiguration() (*Configuration, error) {
-	config := Configuration{}
+	_, callerPath, _, _ := runtime.Caller(1)
+
+	options := optionsFromEnvironment(callerPath)
 
 	_, executablePath, _, ok := runtime.Caller(2)
 	if ok {
-		config.sectionName = path.Base(path.Dir(executablePath))
-	}
-
-	err := config.loadConfiguration()
-	if err != nil {
-		return nil, err
+		options.SectionName = path.Base(path.Dir(executablePath))
 	}
 
-	return &config, nil
+	return NewConfigurationWithOptions(options)
 }
 
 func (config *Configuration) SetConfigChangeCallback(callback func([]ChangeDetails)) {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
 	config.configChangeCallback = callback
 }
 
+// Close stops watching Consul or the local files for changes, waiting for a change being applied to finish.
+// The getters keep returning the last configuration. Close must not be called from a change callback.
+func (config *Configuration) Close() error {
+	// Stop waiting for Consul when running from the cache before stopping the watchers, so none is started after
+	if config.cancel != nil {
+		config.cancel()
+	}
+	config.background.Wait()
+
+	config.mutex.RLock()
+	consulWatcher := config.consulWatcher
+	config.mutex.RUnlock()
+
+	if consulWatcher != nil {
+		consulWatcher.Stop()
+	}
+
+	if config.fileWatcher != nil {
+		config.fileWatcher.Stop()
+	}
+
+	return nil
+}
+
 func (config *Configuration) Load(path string) error {
 	file, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
 
-	err = json.Unmarshal(file, &config.parsedJson)
-	return err
+	parsedJson, err := parseDocument(file, filepath.Ext(path))
+	if err != nil {
+		return err
+	}
+
+	document := parsedJson
+	parsedJson, err = config.prepareDocument(document)
+	if err != nil {
+		return err
+	}
+
+	config.updateMutex.Lock()
+	defer config.updateMutex.Unlock()
+
+	config.setParsedJson(parsedJson, document, 0)
+	return nil
 }
 
+// GetParsedJson returns the current configuration document. The returned map is shared with
+// concurrent readers and must not be modified.
 func (config *Configuration) GetParsedJson() map[string]interface{} {
-	return config.parsedJson
+	return config.parsedJson()
 }
 
 func (config *Configuration) GetNestedJSON(path string) (map[string]interface{}, error) {
-	config.isNestedConfig = true
-	if !config.pathExistsInConfigFile(path) {
-		return nil, fmt.Errorf("%s not found", path)
+	item, fromEnv, err := config.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromEnv {
+		var value map[string]interface{}
+		if err := json.Unmarshal([]byte(item.(string)), &value); err != nil {
+			return nil, fmt.Errorf("unable to convert value for '%s' to a map[string]interface: Value='%v'", path, config.redact(item))
+		}
+
+		return value, nil
 	}
 
-	item := config.getValue(path)
 	value, ok := item.(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("unable to convert value for '%s' to a map[string]interface: Value='%v'", path, item)
+		return nil, fmt.Errorf("unable to convert value for '%s' to a map[string]interface: Value='%v'", path, config.redact(item))
 	}
-	config.isNestedConfig = false
-	return value, nil
 
+	return value, nil
 }
 
 func (config *Configuration) GetNestedMapOfMapString(path string) (map[string]map[string]string, error) {
@@ -131,8 +251,6 @@ func (config *Configuration) GetNestedMapOfMapString(path string) (map[string]ma
 func interfaceToString(values map[string]interface{}) (map[string]string, error) {
 	mapOfString := make(map[string]string)
 	for key, value := range values {
-		valType := reflect.TypeOf(value)
-		fmt.Print(valType)
 		switch value.(type) {
 		case float64:
 			mapOfString[key] = strconv.FormatFloat(value.(float64), 'f', -1, 64)
@@ -149,127 +267,114 @@ func interfaceToString(values map[string]interface{}) (map[string]string, error)
 }
 
 func (config *Configuration) GetString(path string) (string, error) {
-	if !config.pathExistsInConfigFile(path) {
-		value, ok := os.LookupEnv(path)
-		if !ok {
-			return "", fmt.Errorf("%s not found", path)
-		}
-
-		return value, nil
+	item, _, err := config.lookup(path)
+	if err != nil {
+		return "", err
 	}
 
-	item := config.getValue(path)
-
 	value, ok := item.(string)
 	if !ok {
-		return "", fmt.Errorf("unable to convert value for '%s' to a string: Value='%v'", path, item)
+		return "", fmt.Errorf("unable to convert value for '%s' to a string: Value='%v'", path, config.redact(item))
 	}
 
 	return value, nil
 }
 
 func (config *Configuration) GetInt(path string) (int, error) {
-	if !config.pathExistsInConfigFile(path) {
-		value, ok := os.LookupEnv(path)
-		if !ok {
-			return 0, fmt.Errorf("%s not found", path)
-		}
+	item, fromEnv, err := config.lookup(path)
+	if err != nil {
+		return 0, err
+	}
 
-		intValue, err := strconv.Atoi(value)
+	if fromEnv {
+		intValue, err := strconv.Atoi(item.(string))
 		if err != nil {
-			return 0, fmt.Errorf("unable to convert value for '%s' to an int: Value='%v'", path, intValue)
+			return 0, fmt.Errorf("unable to convert value for '%s' to an int: Value='%v'", path, config.redact(item))
 		}
 
 		return intValue, nil
 	}
 
-	item := config.getValue(path)
-
 	value, ok := item.(float64)
 	if !ok {
-		return 0, fmt.Errorf("unable to convert value for '%s' to an int: Value='%v'", path, item)
+		return 0, fmt.Errorf("unable to convert value for '%s' to an int: Value='%v'", path, config.redact(item))
 	}
 
 	return int(value), nil
 }
 
 func (config *Configuration) GetFloat(path string) (float64, error) {
-	if !config.pathExistsInConfigFile(path) {
-		value, ok := os.LookupEnv(path)
-		if !ok {
-			return 0, fmt.Errorf("%s not found", path)
-		}
+	item, fromEnv, err := config.lookup(path)
+	if err != nil {
+		return 0, err
+	}
 
-		floatValue, err := strconv.ParseFloat(value, 64)
+	if fromEnv {
+		floatValue, err := strconv.ParseFloat(item.(string), 64)
 		if err != nil {
-			return 0, fmt.Errorf("unable to convert value for '%s' to an int: Value='%v'", path, value)
+			return 0, fmt.Errorf("unable to convert value for '%s' to an int: Value='%v'", path, config.redact(item))
 		}
 
 		return floatValue, nil
 	}
 
-	item := config.getValue(path)
-
 	value, ok := item.(float64)
 	if !ok {
-		return 0, fmt.Errorf("unable to convert value for '%s' to an int: Value='%v'", path, item)
+		return 0, fmt.Errorf("unable to convert value for '%s' to an int: Value='%v'", path, config.redact(item))
 	}
 
 	return value, nil
 }
 
 func (config *Configuration) GetBool(path string) (bool, error) {
-	if !config.pathExistsInConfigFile(path) {
-		value, ok := os.LookupEnv(path)
-		if !ok {
-			return false, fmt.Errorf("%s not found", path)
-		}
+	item, fromEnv, err := config.lookup(path)
+	if err != nil {
+		return false, err
+	}
 
-		boolValue, err := strconv.ParseBool(value)
+	if fromEnv {
+		boolValue, err := strconv.ParseBool(item.(string))
 		if err != nil {
-			return false, fmt.Errorf("unable to convert value for '%s' to a bool: Value='%v'", path, boolValue)
+			return false, fmt.Errorf("unable to convert value for '%s' to a bool: Value='%v'", path, config.redact(item))
 		}
 
 		return boolValue, nil
 	}
 
-	item := config.getValue(path)
-
 	value, ok := item.(bool)
 	if !ok {
-		return false, fmt.Errorf("unable to convert value for '%s' to a bool: Value='%v'", path, item)
+		return false, fmt.Errorf("unable to convert value for '%s' to a bool: Value='%v'", path, config.redact(item))
 	}
 
 	return value, nil
 }
 
 func (config *Configuration) GetStringSlice(path string) ([]string, error) {
-	if !config.pathExistsInConfigFile(path) {
-		value, ok := os.LookupEnv(path)
-		if !ok {
-			return nil, fmt.Errorf("%s not found", path)
-		}
-
-		value = strings.Replace(value, "[", "", 1)
-		value = strings.Replace(value, "]", "", 1)
+	item, fromEnv, err := config.lookup(path)
+	if err != nil {
+		return nil, err
+	}
 
-		slice := strings.Split(value, ",")
-		var resultSlice []string
-		for _, item := range slice {
-			resultSlice = append(resultSlice, strings.Trim(item, " "))
+	if fromEnv {
+		if items, ok := jsonArray(item.(string)); ok {
+			item, fromEnv = items, false
 		}
+	}
 
-		return resultSlice, nil
+	if fromEnv {
+		return splitEnvList(item.(string)), nil
 	}
 
-	item := config.getValue(path)
-	slice := item.([]interface{})
+	slice, ok := item.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unable to convert value for '%s' to a slice: Value='%v'", path, config.redact(item))
+	}
 
 	var stringSlice []string
 	for _, sliceItem := range slice {
 		value, ok := sliceItem.(string)
 		if !ok {
-			return nil, fmt.Errorf("unable to convert a value for '%s' to a string: Value='%v'", path, sliceItem)
+			return nil, fmt.Errorf("unable to convert a value for '%s' to a string: Value='%v'", path, config.redact(sliceItem))
 
 		}
 		stringSlice = append(stringSlice, value)
@@ -278,189 +383,276 @@ func (config *Configuration) GetStringSlice(path string) ([]string, error) {
 	return stringSlice, nil
 }
 
-func (config *Configuration) getValue(path string) interface{} {
-	if config.parsedJson == nil {
-		return nil
-	}
-
-	if config.sectionName != "" {
-		sectionedPath := fmt.Sprintf("%s.%s", config.sectionName, path)
-		value := config.getValueFromJson(sectionedPath)
-		if value != nil {
-			return value
-		}
+// lookup returns the value for path from the section or global keys of the document. A flag bound to the path
+// and set on the command line, and then an environment variable such as PREFIX_SECTION_KEY, take precedence
+// over the document. When the path is not in the document the environment variable of the same name is used
+// instead. For a flag or environment variable fromEnv is true and the value is the unparsed string. The
+// registered defaults are used last.
+func (config *Configuration) lookup(path string) (item interface{}, fromEnv bool, err error) {
+	item, source, _, found := config.locate(path)
+	if !found {
+		return nil, false, &notFoundError{path: path}
 	}
 
-	value := config.getValueFromJson(path)
-	return value
+	item, err = config.resolveSecrets(path, item)
+	return item, source == EnvironmentSource || source == FlagSource, err
 }
 
-func (config *Configuration) getValueFromJson(path string) interface{} {
-	pathNodes := strings.Split(path, ".")
-	if len(pathNodes) == 0 {
-		return nil
-	}
-
-	var ok bool
-	var value interface{}
-	jsonNodes := config.parsedJson
-	for _, node := range pathNodes {
-		if jsonNodes[node] == nil {
-			return nil
-		}
-
-		item := jsonNodes[node]
-		jsonNodes, ok = item.(map[string]interface{})
-		if ok && !config.isNestedConfig {
-			continue
-		}
-
-		if config.sectionName == node {
-			continue
-		}
+// splitEnvList splits a list held in an environment variable, such as "[one, two]" or "one,two", into its items
+func splitEnvList(value string) []string {
+	value = strings.Replace(value, "[", "", 1)
+	value = strings.Replace(value, "]", "", 1)
 
-		value = item
-		break
+	slice := strings.Split(value, ",")
+	var resultSlice []string
+	for _, item := range slice {
+		resultSlice = append(resultSlice, strings.Trim(item, " "))
 	}
 
-	return value
+	return resultSlice
 }
 
-func (config *Configuration) pathExistsInConfigFile(path string) bool {
-	if config.sectionName != "" {
-		sectionPath := fmt.Sprintf("%s.%s", config.sectionName, path)
-		if config.getValue(sectionPath) != nil {
-			return true
-		}
-	}
-
-	if config.getValue(path) != nil {
-		return true
+func (config *Configuration) parsedJson() map[string]interface{} {
+	snapshot, ok := config.current.Load().(*configSnapshot)
+	if !ok {
+		return nil
 	}
 
-	return false
+	return snapshot.parsedJson
 }
 
-func (config *Configuration) loadConfiguration() error {
-	_, filename, _, ok := runtime.Caller(2)
+func (config *Configuration) modifyIndex() uint64 {
+	snapshot, ok := config.current.Load().(*configSnapshot)
 	if !ok {
-		log.Print("No caller information")
+		return 0
 	}
 
-	absolutePath := path.Join(path.Dir(filename), "configuration.json")
-
-	// By default load local configuration file if it exists
-	if _, err := os.Stat(absolutePath); err != nil {
-		absolutePath, ok = os.LookupEnv("runtimeConfigPath")
-		if !ok {
-			absolutePath = "/run/secrets/configuration.json"
-		}
-		if _, err := os.Stat(absolutePath); err != nil {
-			absolutePath = ""
-		}
-	}
-
-	consulUrl, urlOk := os.LookupEnv("consulUrl")
-	consulConfigKey, keyOk := os.LookupEnv("consulConfigKey")
-	if urlOk && keyOk {
-		return config.loadFromConsul(absolutePath, consulUrl, consulConfigKey)
-	}
+	return snapshot.modifyIndex
+}
 
-	log.Print("consulUrl and/or consulConfigKey environment variable not set, using local configuration file")
+// setParsedJson publishes a new document to readers. Callers must hold updateMutex.
+func (config *Configuration) setParsedJson(parsedJson map[string]interface{}, document map[string]interface{}, modifyIndex uint64) {
+	config.current.Store(&configSnapshot{parsedJson: parsedJson, document: document, modifyIndex: modifyIndex})
+	config.recordVersion(document, modifyIndex)
+}
 
-	if absolutePath != "" {
-		err := config.Load(absolutePath)
-		if err != nil {
-			return err
-		}
-	}
+func (config *Configuration) getValue(path string) (interface{}, bool) {
+	return getSectionValue(config.parsedJson(), config.sectionName, path)
+}
 
-	return nil
+func (config *Configuration) pathExistsInConfigFile(path string) bool {
+	_, found := config.getValue(path)
+	return found
 }
 
-func (config *Configuration) loadFromConsul(configFilePath string, consulUrl string, consulConfigKey string) error {
+func (config *Configuration) loadFromConsul(ctx context.Context, layers []string, consulUrl string, consulConfigKey string, tree bool) error {
 
 	consul, clientErr := consulApi.NewClient(&consulApi.Config{Address: consulUrl})
 	if clientErr != nil {
 		return fmt.Errorf("not able to communicate with Consul service: %s", clientErr.Error())
 	}
 
-	keyValuePair, checkErr := checkAndUpdateFromLocal(consul, consulConfigKey, configFilePath)
+	// Set before the configuration is applied so it is cached for this key
+	config.consul = consul
+	config.consulKey = consulConfigKey
+	config.consulTree = tree
+
+	var keyValuePair *consulApi.KeyValuePair
+	var checkErr error
+	if tree {
+		keyValuePair, checkErr = checkAndUpdateTreeFromLocal(consul, consulConfigKey, layers)
+	} else {
+		keyValuePair, checkErr = checkAndUpdateFromLocal(consul, consulConfigKey, layers)
+	}
 	if checkErr != nil {
-		return checkErr
+		// Start with the last configuration read from Consul, if there is one, rather than not at all
+		cachedKeyValuePair, cacheErr := readCache(config.cachePath, consulConfigKey, tree)
+		if cacheErr != nil {
+			return checkErr
+		}
+
+		log.Printf("WARNING: using configuration cached in %s until Consul Service is reachable: %s", config.cachePath, checkErr.Error())
+		if err := config.applyConfigurationJson(cachedKeyValuePair); err != nil {
+			return fmt.Errorf("error applying configuration cached in %s: %s", config.cachePath, err.Error())
+		}
+
+		config.background.Add(1)
+		go config.reconcileWithConsul(ctx, layers, consul, consulConfigKey, tree)
+		return nil
 	}
 
-	if err := json.Unmarshal(keyValuePair.Value, &config.parsedJson); err != nil {
+	if err := config.applyConfigurationJson(keyValuePair); err != nil {
 		return fmt.Errorf("error marshaling JSON configuration received from/pushed to Consul Service: %s", err.Error())
 	}
 
 	// Now that we know we are using Consul service, we need to create a watch on the configuration for changes.
-	watcher, watcherErr := NewWatcher(consul, consulConfigKey)
+	return config.watchConsul(ctx, consul, consulConfigKey, tree)
+}
+
+// watchConsul starts watching the configuration in Consul for changes until ctx is canceled or Close is called
+func (config *Configuration) watchConsul(ctx context.Context, consul *consulApi.Client, consulConfigKey string, tree bool) error {
+	var watcher *Watcher
+	var watcherErr error
+	if tree {
+		watcher, watcherErr = NewTreeWatcherWithContext(ctx, consul, consulConfigKey)
+	} else {
+		watcher, watcherErr = NewWatcherWithContext(ctx, consul, consulConfigKey)
+	}
 	if watcherErr != nil {
 		return fmt.Errorf("error creating watcher for chnages to value for %s: %s", consulConfigKey, watcherErr.Error())
 	}
 
-	if err := watcher.Start(config.processConfigurationChanged); err != nil {
+	if err := watcher.startWatch(config.processKeyValueChanged); err != nil {
 		return fmt.Errorf("error starting watcher for chnages to value for %s: %s", consulConfigKey, err.Error())
 	}
 
+	config.mutex.Lock()
+	config.consulWatcher = watcher
+	config.mutex.Unlock()
+
 	return nil
 }
 
-func (config *Configuration) applyConfigurationJson(jsonBytes []byte) error {
+func (config *Configuration) applyConfigurationJson(keyValuePair *consulApi.KeyValuePair) error {
+	config.updateMutex.Lock()
+	defer config.updateMutex.Unlock()
 
-	// Clear parsed JSON so start fresh since old deleted fields don't get removed.
-	config.parsedJson = map[string]interface{}{}
+	// Parse into a fresh map so old deleted fields don't carry over and readers never see a partial document.
+	parsedJson, err := parseDocument(keyValuePair.Value, "")
+	if err != nil {
+		return err
+	}
 
-	return json.Unmarshal(jsonBytes, &config.parsedJson)
+	document := parsedJson
+	parsedJson, err = config.prepareDocument(document)
+	if err != nil {
+		return err
+	}
+
+	config.setParsedJson(parsedJson, document, keyValuePair.ModifyIndex)
+	config.saveCache(document, keyValuePair.ModifyIndex)
+	return nil
 }
 
 func (config *Configuration) processConfigurationChanged(configurationJson []byte) {
+	config.processKeyValueChanged(&consulApi.KeyValuePair{Value: configurationJson})
+}
 
-	// Have to get these before applying new configuration JSON for comparing later
-	previousGlobalSection, previousTargetSection := config.getGlobalAndTargetSections()
+func (config *Configuration) processKeyValueChanged(keyValuePair *consulApi.KeyValuePair) {
+	// A rejected update has already been reported
+	_ = config.applyKeyValuePair(keyValuePair)
+}
+
+// applyKeyValuePair applies a changed document read from Consul and returns the error when it was rejected
+func (config *Configuration) applyKeyValuePair(keyValuePair *consulApi.KeyValuePair) error {
+	// Deferred first so the notifications are delivered after updateMutex is released
+	defer config.deliverNotifications()
+
+	// Serialize updates so change notifications are delivered in the order the updates were applied.
+	config.updateMutex.Lock()
+	defer config.updateMutex.Unlock()
 
-	// This saves the new configuration
-	if err := config.applyConfigurationJson(configurationJson); err != nil {
+	return config.applyChangedPair(keyValuePair, nil)
+}
+
+// applyChangedPair is applyKeyValuePair for callers holding updateMutex. The change handlers aren't called
+// again when the document is accepted, the document they already accepted.
+func (config *Configuration) applyChangedPair(keyValuePair *consulApi.KeyValuePair, accepted map[string]interface{}) error {
+	// The watcher and a write back can both read the same change, possibly out of order
+	if keyValuePair.ModifyIndex != 0 && keyValuePair.ModifyIndex < config.modifyIndex() {
+		return nil
+	}
+
+	// A rejected change isn't applied again when it is read a second time
+	if keyValuePair.ModifyIndex != 0 && keyValuePair.ModifyIndex == config.rejectedIndex {
+		return config.rejectedErr
+	}
+
+	parsedJson, err := parseDocument(keyValuePair.Value, "")
+	if err != nil {
 		log.Printf("error marshaling JSON configuration received from change Consul watcher: %s", err.Error())
+		return err
 	}
 
-	// if callback not set there is no need to continue the processing looking if anything changed.
-	if config.configChangeCallback == nil {
-		return
+	return config.applyUpdate(parsedJson, keyValuePair.ModifyIndex, accepted)
+}
+
+// processFilesChanged applies the configuration reloaded by the FileWatcher from the local files
+func (config *Configuration) processFilesChanged(parsedJson map[string]interface{}) {
+	defer config.deliverNotifications()
+
+	config.updateMutex.Lock()
+	defer config.updateMutex.Unlock()
+
+	_ = config.applyUpdate(parsedJson, 0, nil)
+}
+
+// applyUpdate validates and saves an updated document and queues the notifications of what changed. The error
+// is returned when the update was rejected, either as invalid or by a change handler. The handlers are skipped
+// when the document is accepted, which they have already accepted. Callers must hold updateMutex, and call
+// deliverNotifications once it is released.
+func (config *Configuration) applyUpdate(parsedJson map[string]interface{}, modifyIndex uint64, accepted map[string]interface{}) error {
+	// An invalid update is dropped so the last known good configuration stays in use
+	document := parsedJson
+	parsedJson, err := config.prepareDocument(document)
+	if err != nil {
+		config.rejectUpdate(err)
+		config.rejectedIndex, config.rejectedErr = modifyIndex, err
+		return err
 	}
 
-	var changedList []ChangeDetails
-	newGlobalSection, newTargetSection := config.getGlobalAndTargetSections()
+	changedList := config.changesTo(parsedJson)
 
-	changedList = config.getChanges(changedList, previousGlobalSection, newGlobalSection, false)
-	changedList = config.getChanges(changedList, previousTargetSection, newTargetSection, true)
+	// The handlers run before the update is published, so a rejected update is never seen by the getters
+	if len(changedList) > 0 && (accepted == nil || !reflect.DeepEqual(accepted, document)) {
+		if err := config.callChangeHandlers(changedList); err != nil {
+			config.rejectUpdate(err)
+			config.rejectedIndex, config.rejectedErr = modifyIndex, err
+			return err
+		}
+	}
+
+	config.setParsedJson(parsedJson, document, modifyIndex)
+	config.saveCache(document, modifyIndex)
 
 	if len(changedList) > 0 {
-		config.configChangeCallback(changedList)
+		config.queueNotification(func() {
+			config.notifyChanges(changedList, parsedJson)
+		})
 	}
+	return nil
 }
 
-func (config *Configuration) getGlobalAndTargetSections() (map[string]interface{}, map[string]interface{}) {
+// changesTo returns the changes from the current configuration to the prepared document
+func (config *Configuration) changesTo(parsedJson map[string]interface{}) []ChangeDetails {
+	previous, _ := config.current.Load().(*configSnapshot)
+	if previous == nil {
+		previous = &configSnapshot{}
+	}
+	previousGlobalSection, previousTargetSection := config.getGlobalAndTargetSections(previous.parsedJson)
+	newGlobalSection, newTargetSection := config.getGlobalAndTargetSections(parsedJson)
 
+	var changedList []ChangeDetails
+	changedList = config.getChanges(changedList, previousGlobalSection, newGlobalSection, false)
+	return config.getChanges(changedList, previousTargetSection, newTargetSection, true)
+}
+
+// getGlobalAndTargetSections splits the document into the global keys, including nested objects such as
+// "influx", and the keys of the active section. The sections of other services are left out.
+func (config *Configuration) getGlobalAndTargetSections(parsedJson map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
 	globalSection := make(map[string]interface{})
 	targetSection := make(map[string]interface{})
 
-	for configItemName, configItemValue := range config.parsedJson {
-		configValueDetail := reflect.ValueOf(configItemValue)
-		kind := configValueDetail.Kind()
-
-		if kind == reflect.Map {
-			if configItemName == config.sectionName {
-				for _, key := range configValueDetail.MapKeys() {
-					valueFromMap := configValueDetail.MapIndex(key)
-					name := key.Interface().(string)
-					value := valueFromMap.Elem().Interface()
-
-					targetSection[name] = value
-				}
+	for configItemName, configItemValue := range parsedJson {
+		section, ok := configItemValue.(map[string]interface{})
+		switch {
+		case ok && configItemName == config.sectionName:
+			for name, value := range section {
+				targetSection[name] = value
 			}
-		} else {
+		case ok && isSectionName(configItemName):
+			continue
+		default:
 			globalSection[configItemName] = configItemValue
 		}
 	}
@@ -468,53 +660,23 @@ func (config *Configuration) getGlobalAndTargetSections() (map[string]interface{
 	return globalSection, targetSection
 }
 
-func (config *Configuration) getChanges(changedList []ChangeDetails, previousSection map[string]interface{}, newSection map[string]interface{}, isTargetSection bool) []ChangeDetails {
-	for itemName, itemValue := range previousSection {
-		name := itemName
-		if isTargetSection {
-			name = config.sectionName + "." + itemName
-		}
-
-		if newSection[itemName] == nil {
-			details := ChangeDetails{
-				Name:      name,
-				Value:     nil,
-				Operation: Deleted,
-			}
-			changedList = append(changedList, details)
-			continue
-		}
-
-		if itemValue != newSection[itemName] {
-			details := ChangeDetails{
-				Name:      name,
-				Value:     newSection[itemName],
-				Operation: Updated,
-			}
-			changedList = append(changedList, details)
-		}
-	}
-
-	for itemName, itemValue := range newSection {
-		name := itemName
-		if isTargetSection {
-			name = config.sectionName + "." + itemName
-		}
+// isSectionName returns whether a top-level object is the section of a service. Sections are named after the
+// service's directory, such as "inventory-service", while keys are camel case like "loggingLevel", so an
+// object whose name has a '-' is taken to be a section.
+func isSectionName(name string) bool {
+	return strings.Contains(name, "-")
+}
 
-		if previousSection[itemName] == nil {
-			details := ChangeDetails{
-				Name:      name,
-				Value:     itemValue,
-				Operation: Added,
-			}
-			changedList = append(changedList, details)
-		}
+func (config *Configuration) getChanges(changedList []ChangeDetails, previousSection map[string]interface{}, newSection map[string]interface{}, isTargetSection bool) []ChangeDetails {
+	prefix := ""
+	if isTargetSection {
+		prefix = config.sectionName
 	}
 
-	return changedList
+	return appendObjectChanges(changedList, prefix, previousSection, newSection)
 }
 
-func checkAndUpdateFromLocal(consul *consulApi.Client, consulConfigKey string, configFilePath string) (*consulApi.KeyValuePair, error) {
+func checkAndUpdateFromLocal(consul *consulApi.Client, consulConfigKey string, layers []string) (*consulApi.KeyValuePair, error) {
 	keyValuePair, err := consul.GetValue(consulConfigKey, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error attempting to get '%s' value from Consul service: %s", consulConfigKey, err.Error())
@@ -523,20 +685,29 @@ func checkAndUpdateFromLocal(consul *consulApi.Client, consulConfigKey string, c
 	if keyValuePair == nil {
 		log.Printf("%s not found in Consul Service. Attempting to push local default configuration to Consul Service", consulConfigKey)
 
-		// Load the local default configuration file in order to push it to Consul.
-		fileBytes, readErr := ioutil.ReadFile(configFilePath)
+		// Load and merge the local default configuration files in order to push them to Consul.
+		parsedJson, readErr := loadLayers(layers)
 		if readErr != nil {
 			return nil, fmt.Errorf("error attempting to load default configuration inorder to push to Consul Service: %s", readErr.Error())
 		}
 
-		keyValuePair = &consulApi.KeyValuePair{
-			Key:   consulConfigKey,
-			Value: fileBytes,
+		fileBytes, marshalErr := json.MarshalIndent(parsedJson, "", "  ")
+		if marshalErr != nil {
+			return nil, fmt.Errorf("error attempting to load default configuration inorder to push to Consul Service: %s", marshalErr.Error())
 		}
 
 		if putErr := consul.PutValue(consulConfigKey, string(fileBytes)); putErr != nil {
 			return nil, fmt.Errorf("error pushing default configuration to '%s' value in Consul Service: %s", consulConfigKey, putErr.Error())
 		}
+
+		// Read the value back for the ModifyIndex Consul assigned to it
+		keyValuePair, err = consul.GetValue(consulConfigKey, nil)
+		if err != nil || keyValuePair == nil {
+			keyValuePair = &consulApi.KeyValuePair{
+				Key:   consulConfigKey,
+				Value: fileBytes,
+			}
+		}
 	}
 
 	return keyValuePair, nil
@@ -7,10 +7,12 @@
 package configuration
 
 import (
+	"fmt"
 	"os"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/consulApi"
@@ -41,7 +43,7 @@ func TestLoadDefaultConfig(t *testing.T) {
 		t.Errorf("NewConfiguration returned error %s", err.Error())
 	}
 
-	if target.parsedJson == nil {
+	if target.parsedJson() == nil {
 		t.Error("Parsed JSON is missing")
 	}
 }
@@ -59,7 +61,7 @@ func TestLoadSimple(t *testing.T) {
 		t.Fatalf("Config file not loaded: %s", err.Error())
 	}
 
-	if target.parsedJson == nil {
+	if target.parsedJson() == nil {
 		t.Error("Parsed JSON is missing")
 	}
 }
@@ -77,7 +79,7 @@ func TestLoadComplex(t *testing.T) {
 		t.Fatalf("Config file not loaded: %s", err.Error())
 	}
 
-	if target.parsedJson == nil {
+	if target.parsedJson() == nil {
 		t.Error("Parsed JSON is missing")
 	}
 }
@@ -95,7 +97,7 @@ func TestLoadNestedConfig(t *testing.T) {
 		t.Fatalf("Config file not loaded: %s", err.Error())
 	}
 
-	if target.parsedJson == nil {
+	if target.parsedJson() == nil {
 		t.Error("Parsed JSON is missing")
 	}
 }
@@ -1621,6 +1623,144 @@ func TestConfiguration_ConsulConfigNonTargetSectionChangedNotNotified(t *testing
 	}
 }
 
+func TestConfiguration_ConsulConfigNestedChangesNotified(t *testing.T) {
+	restoreDefaultConfiguration()
+
+	section := "unit-test"
+	appConfigValue := "{\"name\" : \"Default Config Unit Test\", \"list\": [\"one\"], \"" + section + "\" : {\"influx\" : {\"url\" : \"http://old:8086\", \"database\" : \"inventory\"}}}"
+
+	appConfigKey := "config/unit-test-app-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	os.Setenv("consulConfigKey", appConfigKey)
+
+	ensureConfigInConsul(consulUrl, appConfigKey, appConfigValue, t)
+
+	var actualChanges []ChangeDetails
+	doneChannel := make(chan bool)
+
+	target, err := NewSectionedConfiguration(section)
+	if err != nil {
+		t.Fatalf("NewConfiguration returned error %s", err.Error())
+	}
+
+	target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+		actualChanges = changes
+		doneChannel <- true
+	})
+
+	// Give watcher time to spin up
+	time.Sleep(time.Second * 1)
+
+	appConfigValue = "{\"name\" : \"Default Config Unit Test\", \"list\": [\"one\", \"two\"], \"" + section + "\" : {\"influx\" : {\"url\" : \"http://new:8086\"}}}"
+	ensureConfigInConsul(consulUrl, appConfigKey, appConfigValue, t)
+
+	select {
+	case <-doneChannel:
+	case <-time.After(ConsulTime):
+		t.Fatal("never received expected config changed callback")
+	}
+
+	expected := []ChangeDetails{
+		{Name: "list", Value: []interface{}{"one", "two"}, OldValue: []interface{}{"one"}, Operation: Updated},
+		{Name: "unit-test.influx.database", OldValue: "inventory", Operation: Deleted},
+		{Name: "unit-test.influx.url", Value: "http://new:8086", OldValue: "http://old:8086", Operation: Updated},
+	}
+
+	if !reflect.DeepEqual(expected, actualChanges) {
+		t.Errorf("changes not as expected.\nExpected='%+v'\nActual='%+v'", expected, actualChanges)
+	}
+}
+
+// Run with "go test -race" to detect readers observing a partially applied document.
+func TestConfiguration_ConcurrentReadsDuringConsulUpdates(t *testing.T) {
+	restoreDefaultConfiguration()
+
+	section := "unit-test"
+	updateCount := 20
+	configFormat := "{\"name\" : \"Default Config Unit Test\", \"port\": \"%d\", \"version\": %d, \"nested\" : {\"version\" : %d}, \"" + section + "\" : {\"version\" : %d}}"
+
+	appConfigKey := "config/unit-test-app-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	os.Setenv("consulConfigKey", appConfigKey)
+
+	ensureConfigInConsul(consulUrl, appConfigKey, fmt.Sprintf(configFormat, 0, 0, 0, 0), t)
+
+	target, err := NewSectionedConfiguration(section)
+	if err != nil {
+		t.Fatalf("NewConfiguration returned error %s", err.Error())
+	}
+
+	doneChannel := make(chan bool)
+	target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+		version, _ := target.GetInt("version")
+		if version == updateCount {
+			close(doneChannel)
+		}
+	})
+
+	stopChannel := make(chan bool)
+	var readers sync.WaitGroup
+	for reader := 0; reader < 8; reader++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stopChannel:
+					return
+				default:
+				}
+
+				// Every value in a single document has the same version, so any mismatch means a torn read.
+				parsedJson := target.GetParsedJson()
+				globalVersion := parsedJson["version"]
+				sectionVersion := parsedJson[section].(map[string]interface{})["version"]
+				if globalVersion != sectionVersion {
+					t.Errorf("read partially applied document: global version %v, section version %v", globalVersion, sectionVersion)
+					return
+				}
+
+				if _, err := target.GetString("port"); err != nil {
+					t.Errorf("GetString returned error during update: %s", err.Error())
+					return
+				}
+				if _, err := target.GetInt("version"); err != nil {
+					t.Errorf("GetInt returned error during update: %s", err.Error())
+					return
+				}
+				if _, err := target.GetNestedJSON("nested"); err != nil {
+					t.Errorf("GetNestedJSON returned error during update: %s", err.Error())
+					return
+				}
+			}
+		}()
+	}
+
+	// Give watcher time to spin up
+	time.Sleep(time.Second * 1)
+
+	for version := 1; version <= updateCount; version++ {
+		ensureConfigInConsul(consulUrl, appConfigKey, fmt.Sprintf(configFormat, 8000+version, version, version, version), t)
+		time.Sleep(time.Millisecond * 20)
+	}
+
+	select {
+	case <-doneChannel:
+	case <-time.After(ConsulTime):
+		t.Errorf("never received the final config changed callback")
+	}
+
+	close(stopChannel)
+	readers.Wait()
+
+	actual, err := target.GetInt("version")
+	if err != nil {
+		t.Fatalf("version not found as expected: %s", err.Error())
+	}
+
+	if actual != updateCount {
+		t.Errorf("version actual value='%d' not as expected='%d'", actual, updateCount)
+	}
+}
+
 func ensureConfigInConsul(consulUrl string, configKey string, config string, t *testing.T) {
 	consul, err := consulApi.NewClient(&consulApi.Config{Address: consulUrl})
 	if err != nil {
@@ -7,6 +7,7 @@
 package configuration
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -21,9 +22,21 @@ const (
 type Watcher struct {
 	watchKey string
 	consul   *consulApi.Client
+	// recurse watches every key beneath watchKey rather than the key itself
+	recurse bool
+
+	// ctx is canceled by Stop, which waits for done to be closed by the watch goroutine
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
 func NewWatcher(consul *consulApi.Client, key string) (*Watcher, error) {
+	return NewWatcherWithContext(context.Background(), consul, key)
+}
+
+// NewWatcherWithContext creates a Watcher that stops watching when ctx is canceled, as well as when Stop is called
+func NewWatcherWithContext(ctx context.Context, consul *consulApi.Client, key string) (*Watcher, error) {
 	if consul == nil {
 		return nil, fmt.Errorf("consul can not be nil")
 	}
@@ -36,11 +49,63 @@ func NewWatcher(consul *consulApi.Client, key string) (*Watcher, error) {
 		consul:   consul,
 		watchKey: key,
 	}
+	watcher.ctx, watcher.cancel = context.WithCancel(ctx)
 
 	return &watcher, nil
 }
 
 func (watcher *Watcher) Start(changeCallback func([]byte)) error {
+	return watcher.startWatch(func(keyValuePair *consulApi.KeyValuePair) {
+		changeCallback(keyValuePair.Value)
+	})
+}
+
+// Stop cancels the blocking query in progress and waits for the watch goroutine to exit, including any
+// callback it is running, so it must not be called from the change callback. No callbacks are made once
+// Stop returns.
+func (watcher *Watcher) Stop() {
+	watcher.cancel()
+
+	if watcher.done != nil {
+		<-watcher.done
+	}
+}
+
+// NewTreeWatcher creates a Watcher for every key beneath prefix. Each change is passed to the callback as a
+// JSON document with a node for each key, as described by Options.ConsulTree.
+func NewTreeWatcher(consul *consulApi.Client, prefix string) (*Watcher, error) {
+	return NewTreeWatcherWithContext(context.Background(), consul, prefix)
+}
+
+// NewTreeWatcherWithContext creates a tree Watcher that stops watching when ctx is canceled
+func NewTreeWatcherWithContext(ctx context.Context, consul *consulApi.Client, prefix string) (*Watcher, error) {
+	watcher, err := NewWatcherWithContext(ctx, consul, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher.watchKey = treePrefix(prefix)
+	watcher.recurse = true
+
+	return watcher, nil
+}
+
+// waitToRetry waits before querying Consul again after an error and returns false when stopped instead
+func (watcher *Watcher) waitToRetry() bool {
+	select {
+	case <-time.After(time.Second * 10): // Assume consul is restarting, so want long wait.
+		return true
+	case <-watcher.ctx.Done():
+		return false
+	}
+}
+
+// startWatch is Start for callers that also need the ModifyIndex of each change
+func (watcher *Watcher) startWatch(changeCallback func(*consulApi.KeyValuePair)) error {
+	if watcher.recurse {
+		return watcher.startTreeWatch(changeCallback)
+	}
+
 	keyValuePair, err := watcher.consul.GetValue(watcher.watchKey, nil)
 	if err != nil {
 		return fmt.Errorf("unable to GET watch key (%s) data: %s", watcher.watchKey, err.Error())
@@ -50,18 +115,35 @@ func (watcher *Watcher) Start(changeCallback func([]byte)) error {
 		return fmt.Errorf("unable to GET watch key (%s) data: key is not found", watcher.watchKey)
 	}
 
+	watcher.done = make(chan struct{})
 	go func(targetIndex uint64) {
+		defer close(watcher.done)
 
-		queryOptions := consulApi.QueryOptions{
+		queryOptions := (&consulApi.QueryOptions{
 			WaitIndex: targetIndex,
 			WaitTime:  watchTimeout,
-		}
+		}).WithContext(watcher.ctx)
 
-		for {
-			keyValuePair, err = watcher.consul.GetValue(watcher.watchKey, &queryOptions)
+		for watcher.ctx.Err() == nil {
+			keyValuePair, err = watcher.consul.GetValue(watcher.watchKey, queryOptions)
 			if err != nil {
+				if watcher.ctx.Err() != nil {
+					return
+				}
 				log.Printf("Error watching %s key: %s", watcher.watchKey, err.Error())
-				time.Sleep(time.Second * 10) // Assume consul is restarting, so want long wait.
+				if !watcher.waitToRetry() {
+					return
+				}
+				continue
+			}
+
+			if keyValuePair == nil {
+				// The key was deleted. Consul answers straight away rather than blocking while the key is missing,
+				// so wait before checking whether it was added again.
+				log.Printf("Watched key %s not found", watcher.watchKey)
+				if !watcher.waitToRetry() {
+					return
+				}
 				continue
 			}
 
@@ -70,12 +152,61 @@ func (watcher *Watcher) Start(changeCallback func([]byte)) error {
 				continue
 			}
 
-			changeCallback(keyValuePair.Value)
+			changeCallback(keyValuePair)
 
 			// This is required so we block waiting for the next change
 			targetIndex = keyValuePair.ModifyIndex
+			queryOptions.WaitIndex = targetIndex
 		}
 	}(keyValuePair.ModifyIndex)
 
 	return nil
 }
+
+func (watcher *Watcher) startTreeWatch(changeCallback func(*consulApi.KeyValuePair)) error {
+	_, queryMeta, err := watcher.consul.ListValues(watcher.watchKey, nil)
+	if err != nil {
+		return fmt.Errorf("unable to list keys beneath watch prefix (%s): %s", watcher.watchKey, err.Error())
+	}
+
+	watcher.done = make(chan struct{})
+	go func(targetIndex uint64) {
+		defer close(watcher.done)
+
+		queryOptions := (&consulApi.QueryOptions{
+			WaitIndex: targetIndex,
+			WaitTime:  watchTimeout,
+		}).WithContext(watcher.ctx)
+
+		for watcher.ctx.Err() == nil {
+			keyValuePairs, queryMeta, err := watcher.consul.ListValues(watcher.watchKey, queryOptions)
+			if err != nil {
+				if watcher.ctx.Err() != nil {
+					return
+				}
+				log.Printf("Error watching keys beneath %s: %s", watcher.watchKey, err.Error())
+				if !watcher.waitToRetry() {
+					return
+				}
+				continue
+			}
+
+			if queryMeta.LastIndex == queryOptions.WaitIndex {
+				// No change , so must have timed out. Try again
+				continue
+			}
+
+			documentJson, err := treeDocumentJson(watcher.watchKey, keyValuePairs)
+			if err != nil {
+				log.Printf("Error building configuration from keys beneath %s: %s", watcher.watchKey, err.Error())
+			} else {
+				changeCallback(&consulApi.KeyValuePair{Key: watcher.watchKey, Value: documentJson, ModifyIndex: queryMeta.LastIndex})
+			}
+
+			// This is required so we block waiting for the next change
+			queryOptions.WaitIndex = queryMeta.LastIndex
+		}
+	}(queryMeta.LastIndex)
+
+	return nil
+}
@@ -7,11 +7,14 @@
 package configuration
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -159,3 +162,86 @@ func TestStartNoConsulRunning(t *testing.T) {
 		t.Error("expecting error for consul not running")
 	}
 }
+
+func TestStop(t *testing.T) {
+	for _, tree := range []bool{false, true} {
+		appConfigKey := "config/unit-test-stop"
+		ensureConfigInConsul(consulUrl, appConfigKey, `{"port": "8080"}`, t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var target *Watcher
+		var err error
+		if tree {
+			target, err = NewTreeWatcherWithContext(ctx, consul, appConfigKey)
+		} else {
+			target, err = NewWatcherWithContext(ctx, consul, appConfigKey)
+		}
+		if err != nil {
+			t.Fatalf("NewWatcherWithContext returned error %s", err.Error())
+		}
+
+		changes := make(chan *consulApi.KeyValuePair, 10)
+		if err := target.startWatch(func(keyValuePair *consulApi.KeyValuePair) {
+			changes <- keyValuePair
+		}); err != nil {
+			t.Fatalf("Watcher not started: %s", err.Error())
+		}
+
+		// Let the watcher block waiting for a change before it is stopped
+		time.Sleep(time.Millisecond * 100)
+		stopped := make(chan bool)
+		go func() {
+			if tree {
+				cancel()
+				<-target.done
+			} else {
+				target.Stop()
+			}
+			stopped <- true
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(time.Second * 5):
+			t.Fatal("Timed out waiting for watcher to stop")
+		}
+
+		ensureConfigInConsul(consulUrl, appConfigKey, `{"port": "8085"}`, t)
+		select {
+		case keyValuePair := <-changes:
+			t.Errorf("Expected no changes after Stop, got %s", keyValuePair.Value)
+		case <-time.After(time.Millisecond * 500):
+		}
+
+		// Stop can be called again, including after the context was canceled
+		target.Stop()
+		cancel()
+	}
+}
+
+func TestWatchDeletedKey(t *testing.T) {
+	// Consul answers a blocking query for a deleted key with a 404 straight away
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			fmt.Fprint(writer, `[{"Key": "config/unit-test-deleted", "Value": "e30=", "ModifyIndex": 5}]`)
+			return
+		}
+		http.NotFound(writer, request)
+	}))
+	defer server.Close()
+
+	deletedConsul, _ := consulApi.NewClient(&consulApi.Config{Address: server.URL + "/v1/kv"})
+	target, _ := NewWatcher(deletedConsul, "config/unit-test-deleted")
+	if err := target.startWatch(func(*consulApi.KeyValuePair) {}); err != nil {
+		t.Fatalf("Watcher not started: %s", err.Error())
+	}
+
+	time.Sleep(time.Millisecond * 300)
+	target.Stop()
+
+	// The first GET reads the key and the second finds it deleted, then the watcher waits before retrying
+	if actual := atomic.LoadInt32(&requests); actual > 2 {
+		t.Errorf("Expected the watcher to wait after finding the key deleted, got %d requests", actual)
+	}
+}
@@ -0,0 +1,148 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/consulApi"
+)
+
+const treeSeparator = "/"
+
+// treePrefix returns the prefix with a trailing separator so config/inventory doesn't match config/inventory2
+func treePrefix(prefix string) string {
+	return strings.TrimSuffix(prefix, treeSeparator) + treeSeparator
+}
+
+// documentFromTree builds a document with a node for each key beneath the prefix. Values that are valid JSON,
+// such as 8080, true or "8080", are decoded, and any other value is a string. A key with keys beneath it is
+// an object, so a value stored on the same key is ignored.
+func documentFromTree(prefix string, keyValuePairs consulApi.KeyValuePairs) map[string]interface{} {
+	prefix = treePrefix(prefix)
+
+	document := make(map[string]interface{})
+	for _, keyValuePair := range keyValuePairs {
+		relativeKey := strings.TrimPrefix(keyValuePair.Key, prefix)
+		if relativeKey == keyValuePair.Key || relativeKey == "" || strings.HasSuffix(relativeKey, treeSeparator) {
+			// The prefix itself and folders
+			continue
+		}
+
+		var value interface{}
+		if err := json.Unmarshal(keyValuePair.Value, &value); err != nil {
+			value = string(keyValuePair.Value)
+		}
+
+		nodes := strings.Split(relativeKey, treeSeparator)
+		parent := document
+		for _, node := range nodes[:len(nodes)-1] {
+			child, ok := parent[node].(map[string]interface{})
+			if !ok {
+				if _, exists := parent[node]; exists {
+					log.Printf("Consul key %s has keys beneath it, so its value is ignored", prefix+strings.Join(nodes[:len(nodes)-1], treeSeparator))
+				}
+				child = make(map[string]interface{})
+				parent[node] = child
+			}
+			parent = child
+		}
+
+		last := nodes[len(nodes)-1]
+		if _, isObject := parent[last].(map[string]interface{}); isObject {
+			log.Printf("Consul key %s has keys beneath it, so its value is ignored", keyValuePair.Key)
+			continue
+		}
+		parent[last] = value
+	}
+
+	return document
+}
+
+func treeDocumentJson(prefix string, keyValuePairs consulApi.KeyValuePairs) ([]byte, error) {
+	return json.Marshal(documentFromTree(prefix, keyValuePairs))
+}
+
+// treeFromDocument returns a key beneath the prefix for each leaf of the document, holding the value as JSON
+func treeFromDocument(prefix string, document map[string]interface{}) (map[string]string, error) {
+	tree := make(map[string]string)
+	if err := addTreeKeys(tree, treePrefix(prefix), document); err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
+
+func addTreeKeys(tree map[string]string, prefix string, object map[string]interface{}) error {
+	for key, value := range object {
+		if child, ok := value.(map[string]interface{}); ok && len(child) > 0 {
+			if err := addTreeKeys(tree, prefix+key+treeSeparator, child); err != nil {
+				return err
+			}
+			continue
+		}
+
+		valueJson, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("unable to marshal value for %s: %s", prefix+key, err.Error())
+		}
+		tree[prefix+key] = string(valueJson)
+	}
+
+	return nil
+}
+
+func checkAndUpdateTreeFromLocal(consul *consulApi.Client, consulConfigKey string, layers []string) (*consulApi.KeyValuePair, error) {
+	prefix := treePrefix(consulConfigKey)
+
+	keyValuePairs, queryMeta, err := consul.ListValues(prefix, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error attempting to list keys beneath '%s' from Consul service: %s", prefix, err.Error())
+	}
+
+	if len(keyValuePairs) == 0 {
+		log.Printf("No keys found beneath %s in Consul Service. Attempting to push local default configuration to Consul Service", prefix)
+
+		parsedJson, readErr := loadLayers(layers)
+		if readErr != nil {
+			return nil, fmt.Errorf("error attempting to load default configuration inorder to push to Consul Service: %s", readErr.Error())
+		}
+
+		tree, treeErr := treeFromDocument(prefix, parsedJson)
+		if treeErr != nil {
+			return nil, fmt.Errorf("error attempting to load default configuration inorder to push to Consul Service: %s", treeErr.Error())
+		}
+
+		keys := make([]string, 0, len(tree))
+		for key := range tree {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if putErr := consul.PutValue(key, tree[key]); putErr != nil {
+				return nil, fmt.Errorf("error pushing default configuration to '%s' value in Consul Service: %s", key, putErr.Error())
+			}
+		}
+
+		keyValuePairs, queryMeta, err = consul.ListValues(prefix, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error attempting to list keys beneath '%s' from Consul service: %s", prefix, err.Error())
+		}
+	}
+
+	documentJson, err := treeDocumentJson(prefix, keyValuePairs)
+	if err != nil {
+		return nil, fmt.Errorf("error building configuration from keys beneath '%s': %s", prefix, err.Error())
+	}
+
+	return &consulApi.KeyValuePair{Key: prefix, Value: documentJson, ModifyIndex: queryMeta.LastIndex}, nil
+}
@@ -0,0 +1,106 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/consulApi"
+)
+
+func TestDocumentFromTree(t *testing.T) {
+	keyValuePairs := consulApi.KeyValuePairs{
+		{Key: "config/inventory/"},
+		{Key: "config/inventory/port", Value: []byte(`"8080"`)},
+		{Key: "config/inventory/responseLimit", Value: []byte(`10000`)},
+		{Key: "config/inventory/influx/url", Value: []byte(`http://influxdb:8086`)},
+		{Key: "config/inventory/epcFilters", Value: []byte(`["30", "31"]`)},
+		{Key: "config/inventory2/port", Value: []byte(`"9090"`)},
+	}
+
+	expected := parseTestJson(`{
+		"port": "8080",
+		"responseLimit": 10000,
+		"influx": {"url": "http://influxdb:8086"},
+		"epcFilters": ["30", "31"]
+	}`, t)
+
+	actual := documentFromTree("config/inventory", keyValuePairs)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Document not as expected.\nExpected='%v'\nActual='%v'", expected, actual)
+	}
+
+	tree, err := treeFromDocument("config/inventory", expected)
+	if err != nil {
+		t.Fatalf("treeFromDocument returned error %s", err.Error())
+	}
+
+	expectedTree := map[string]string{
+		"config/inventory/port":          `"8080"`,
+		"config/inventory/responseLimit": `10000`,
+		"config/inventory/influx/url":    `"http://influxdb:8086"`,
+		"config/inventory/epcFilters":    `["30","31"]`,
+	}
+	if !reflect.DeepEqual(expectedTree, tree) {
+		t.Errorf("Tree not as expected.\nExpected='%v'\nActual='%v'", expectedTree, tree)
+	}
+}
+
+func TestConsulTreeChangesNotified(t *testing.T) {
+	prefix := "config/unit-test-tree-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	target, err := NewConfigurationWithOptions(Options{
+		SectionName:   "inventory-service",
+		FilePaths:     []string{"./testData/layered/base.json"},
+		ConsulAddress: consulUrl,
+		ConsulKey:     prefix,
+		ConsulTree:    true,
+	})
+	if err != nil {
+		t.Fatalf("NewConfigurationWithOptions returned error %s", err.Error())
+	}
+
+	// The local configuration is pushed as a key per value
+	verifyConfigInConsul(consulUrl, prefix+"/inventory-service/serviceName", t, nil)
+
+	if actual, _ := target.GetString("serviceName"); actual != "RRP Inventory Service" {
+		t.Errorf("serviceName not as expected. Expected='RRP Inventory Service', Actual='%s'", actual)
+	}
+
+	changes := make(chan []ChangeDetails, 10)
+	target.SetConfigChangeCallback(func(changedList []ChangeDetails) {
+		changes <- changedList
+	})
+
+	ensureConfigInConsul(consulUrl, prefix+"/inventory-service/serviceName", `"Site Inventory Service"`, t)
+
+	select {
+	case changedList := <-changes:
+		expected := []ChangeDetails{{Name: "inventory-service.serviceName", Value: "Site Inventory Service", OldValue: "RRP Inventory Service", Operation: Updated}}
+		if !reflect.DeepEqual(expected, changedList) {
+			t.Errorf("Changes not as expected.\nExpected='%v'\nActual='%v'", expected, changedList)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for change notification")
+	}
+
+	// A key outside the section is a global value
+	ensureConfigInConsul(consulUrl, prefix+"/influx/url", `"http://site-influxdb:8086"`, t)
+
+	select {
+	case changedList := <-changes:
+		expected := []ChangeDetails{{Name: "influx.url", Value: "http://site-influxdb:8086", OldValue: "http://influxdb:8086", Operation: Updated}}
+		if !reflect.DeepEqual(expected, changedList) {
+			t.Errorf("Changes not as expected.\nExpected='%v'\nActual='%v'", expected, changedList)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for change notification")
+	}
+}
@@ -0,0 +1,129 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+)
+
+// secretKeyPattern matches the names of keys whose values are hidden by Handler
+var secretKeyPattern = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|api_?key|private_?key)`)
+
+// EffectiveConfiguration is the configuration as seen through the getters, served by Handler. Values holds
+// every key of the section, the global keys and the defaults by path, with the source of each value.
+type EffectiveConfiguration struct {
+	Section     string                 `json:"section"`
+	ModifyIndex uint64                 `json:"modifyIndex"`
+	Values      map[string]Explanation `json:"values"`
+}
+
+// Handler returns an http.Handler serving the EffectiveConfiguration as JSON for debugging. The values of keys
+// that look like they hold secrets, such as passwords and tokens, and any resolved secrets are redacted.
+func (config *Configuration) Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodGet && request.Method != http.MethodHead {
+			writer.Header().Set("Allow", "GET, HEAD")
+			http.Error(writer, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(config.EffectiveConfiguration()); err != nil {
+			log.Printf("error writing effective configuration: %s", err.Error())
+		}
+	})
+}
+
+// EffectiveConfiguration returns the value and source of every key, with secrets redacted as by Handler
+func (config *Configuration) EffectiveConfiguration() EffectiveConfiguration {
+	// Explain every key from the same document
+	snapshot, _ := config.current.Load().(*configSnapshot)
+	if snapshot == nil {
+		snapshot = &configSnapshot{}
+	}
+
+	paths := config.leafPaths(snapshot.parsedJson)
+
+	effective := EffectiveConfiguration{
+		Section:     config.sectionName,
+		ModifyIndex: snapshot.modifyIndex,
+		Values:      make(map[string]Explanation, len(paths)),
+	}
+
+	for path := range paths {
+		value, source, key, found := config.locateIn(snapshot.parsedJson, config.sectionName, path)
+		if !found {
+			continue
+		}
+
+		effective.Values[path] = Explanation{Path: path, Value: config.redactValue(path, value), Source: source, Key: key}
+	}
+
+	return effective
+}
+
+// leafPaths returns the path the getters use for every key of the section, the global keys, the defaults
+// and the bound flags
+func (config *Configuration) leafPaths(parsedJson map[string]interface{}) map[string]bool {
+	paths := make(map[string]bool)
+	for key, value := range parsedJson {
+		if key == config.sectionName {
+			if section, ok := value.(map[string]interface{}); ok {
+				addLeafPaths(paths, "", section)
+				continue
+			}
+		}
+		addLeafPaths(paths, "", map[string]interface{}{key: value})
+	}
+
+	config.mutex.RLock()
+	defer config.mutex.RUnlock()
+
+	addLeafPaths(paths, "", config.defaults)
+	for path := range config.flags {
+		paths[path] = true
+	}
+
+	return paths
+}
+
+// addLeafPaths adds the path of every value beneath the object that is not itself a non-empty object
+func addLeafPaths(paths map[string]bool, prefix string, object map[string]interface{}) {
+	for key, value := range object {
+		path := joinPath(prefix, key)
+		if child, ok := value.(map[string]interface{}); ok && len(child) > 0 {
+			addLeafPaths(paths, path, child)
+			continue
+		}
+		paths[path] = true
+	}
+}
+
+func (config *Configuration) redactValue(path string, value interface{}) interface{} {
+	names, err := splitPath(path)
+	if err != nil {
+		names = []string{path}
+	}
+
+	for _, name := range names {
+		if secretKeyPattern.MatchString(name) {
+			return redactedText
+		}
+	}
+
+	if stringValue, ok := value.(string); ok {
+		return config.secrets.redact(stringValue)
+	}
+
+	return value
+}
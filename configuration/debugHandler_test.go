@@ -0,0 +1,78 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/consulApi"
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/unitTest"
+)
+
+func TestHandler(t *testing.T) {
+	target := &Configuration{sectionName: "inventory-service"}
+	target.processKeyValueChanged(&consulApi.KeyValuePair{
+		Value: []byte(`{
+			"port": "8080",
+			"influx": {"url": "http://influxdb:8086", "password": "hunter2"},
+			"apiToken": "abc123",
+			"inventory-service": {"port": "8081", "epcFilters": ["30"]}
+		}`),
+		ModifyIndex: 42,
+	})
+	if err := target.SetDefaults(map[string]interface{}{"responseLimit": 10000}); err != nil {
+		t.Fatalf("SetDefaults returned error %s", err.Error())
+	}
+
+	result := unitTest.PerformGet("/config", target.Handler(), t)
+
+	var actual struct {
+		Section     string                            `json:"section"`
+		ModifyIndex uint64                            `json:"modifyIndex"`
+		Values      map[string]map[string]interface{} `json:"values"`
+	}
+	if err := json.Unmarshal([]byte(result), &actual); err != nil {
+		t.Fatalf("Unable to parse handler response %s: %s", result, err.Error())
+	}
+
+	if actual.Section != "inventory-service" || actual.ModifyIndex != 42 {
+		t.Errorf("Section and ModifyIndex not as expected, got '%s' and %d", actual.Section, actual.ModifyIndex)
+	}
+
+	expected := map[string]map[string]interface{}{
+		"port":            {"path": "port", "value": "8081", "source": "section", "key": "inventory-service.port"},
+		"epcFilters":      {"path": "epcFilters", "value": []interface{}{"30"}, "source": "section", "key": "inventory-service.epcFilters"},
+		"influx.url":      {"path": "influx.url", "value": "http://influxdb:8086", "source": "global", "key": "influx.url"},
+		"influx.password": {"path": "influx.password", "value": redactedText, "source": "global", "key": "influx.password"},
+		"apiToken":        {"path": "apiToken", "value": redactedText, "source": "global", "key": "apiToken"},
+		"responseLimit":   {"path": "responseLimit", "value": float64(10000), "source": "default", "key": "responseLimit"},
+	}
+	if !reflect.DeepEqual(expected, actual.Values) {
+		t.Errorf("Values not as expected.\nExpected='%v'\nActual='%v'", expected, actual.Values)
+	}
+
+	if strings.Contains(result, "hunter2") || strings.Contains(result, "abc123") {
+		t.Errorf("Expected secrets to be redacted: %s", result)
+	}
+}
+
+func TestHandlerMethodNotAllowed(t *testing.T) {
+	target := &Configuration{}
+
+	request := httptest.NewRequest(http.MethodPost, "/config", nil)
+	recorder := httptest.NewRecorder()
+	target.Handler().ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status %d, got %d", http.StatusMethodNotAllowed, recorder.Code)
+	}
+}
@@ -0,0 +1,281 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+)
+
+type notFoundError struct {
+	path string
+}
+
+func (notFound *notFoundError) Error() string {
+	return fmt.Sprintf("%s not found", notFound.path)
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(*notFoundError)
+	return ok
+}
+
+// SetDefaults registers default values keyed by path, relative to the section the same as the Get functions.
+// Defaults are used when a path is not found in the section, the global keys or the environment.
+// Values are stored as they would be parsed from JSON, with a time.Duration stored as its string form
+// so it can be read back with GetDuration. Calling SetDefaults again adds to or replaces existing defaults.
+func (config *Configuration) SetDefaults(defaults map[string]interface{}) error {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	// Build a new document rather than modifying the current one, which readers may be using
+	newDefaults := make(map[string]interface{})
+	mergeDocuments(newDefaults, config.defaults)
+
+	for path, value := range defaults {
+		normalizedValue, err := normalizeValue(value)
+		if err != nil {
+			return fmt.Errorf("unable to set default for '%s': %s", path, err.Error())
+		}
+
+		if err := setPath(newDefaults, path, normalizedValue); err != nil {
+			return fmt.Errorf("unable to set default for '%s': %s", path, err.Error())
+		}
+	}
+
+	config.defaults = newDefaults
+	return nil
+}
+
+func (config *Configuration) getDefault(path string) (interface{}, bool) {
+	config.mutex.RLock()
+	defaults := config.defaults
+	config.mutex.RUnlock()
+
+	return lookupPath(defaults, path)
+}
+
+// normalizeValue converts a Go value into the types encoding/json produces so it reads back the same as a value from the document
+func normalizeValue(value interface{}) (interface{}, error) {
+	if duration, ok := value.(time.Duration); ok {
+		return duration.String(), nil
+	}
+
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var normalizedValue interface{}
+	if err := json.Unmarshal(jsonBytes, &normalizedValue); err != nil {
+		return nil, err
+	}
+
+	return normalizedValue, nil
+}
+
+// setPath stores value at the path, creating or replacing intermediate objects as needed. Array elements
+// along the path must already exist.
+func setPath(document map[string]interface{}, path string, value interface{}) error {
+	segments, err := splitPath(path)
+	if err != nil {
+		return err
+	}
+
+	var parent interface{} = document
+	for _, segment := range segments[:len(segments)-1] {
+		existing, _ := getChild(parent, segment)
+		child, isContainer := copyContainer(existing)
+		if !isContainer {
+			child = make(map[string]interface{})
+		}
+
+		if err := setChild(parent, segment, child); err != nil {
+			return fmt.Errorf("invalid path '%s': %s", path, err.Error())
+		}
+		parent = child
+	}
+
+	if err := setChild(parent, segments[len(segments)-1], value); err != nil {
+		return fmt.Errorf("invalid path '%s': %s", path, err.Error())
+	}
+
+	return nil
+}
+
+// mergeDocuments copies every value from source into target, merging objects found in both.
+// Objects are copied rather than shared so later changes to target never modify source.
+func mergeDocuments(target map[string]interface{}, source map[string]interface{}) {
+	for key, sourceValue := range source {
+		sourceObject, sourceIsObject := sourceValue.(map[string]interface{})
+		if !sourceIsObject {
+			target[key] = sourceValue
+			continue
+		}
+
+		targetObject, targetIsObject := target[key].(map[string]interface{})
+		if !targetIsObject {
+			targetObject = make(map[string]interface{})
+			target[key] = targetObject
+		}
+		mergeDocuments(targetObject, sourceObject)
+	}
+}
+
+// orDefault logs errors other than not found, which would otherwise be hidden by returning the default value
+func orDefault(path string, err error) {
+	if !isNotFound(err) {
+		log.Printf("using default value for '%s': %s", path, err.Error())
+	}
+}
+
+func (config *Configuration) GetStringOr(path string, defaultValue string) string {
+	value, err := config.GetString(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetIntOr(path string, defaultValue int) int {
+	value, err := config.GetInt(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetInt64Or(path string, defaultValue int64) int64 {
+	value, err := config.GetInt64(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetUintOr(path string, defaultValue uint) uint {
+	value, err := config.GetUint(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetFloatOr(path string, defaultValue float64) float64 {
+	value, err := config.GetFloat(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetBoolOr(path string, defaultValue bool) bool {
+	value, err := config.GetBool(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetDurationOr(path string, defaultValue time.Duration) time.Duration {
+	value, err := config.GetDuration(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetTimeOr(path string, defaultValue time.Time) time.Time {
+	value, err := config.GetTime(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetURLOr(path string, defaultValue *url.URL) *url.URL {
+	value, err := config.GetURL(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetByteSizeOr(path string, defaultValue uint64) uint64 {
+	value, err := config.GetByteSize(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetStringSliceOr(path string, defaultValue []string) []string {
+	value, err := config.GetStringSlice(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetIntSliceOr(path string, defaultValue []int) []int {
+	value, err := config.GetIntSlice(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetFloatSliceOr(path string, defaultValue []float64) []float64 {
+	value, err := config.GetFloatSlice(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetStringMapOr(path string, defaultValue map[string]interface{}) map[string]interface{} {
+	value, err := config.GetStringMap(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetStringMapStringOr(path string, defaultValue map[string]string) map[string]string {
+	value, err := config.GetStringMapString(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
+
+func (config *Configuration) GetNestedJSONOr(path string, defaultValue map[string]interface{}) map[string]interface{} {
+	value, err := config.GetNestedJSON(path)
+	if err != nil {
+		orDefault(path, err)
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,149 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetDefaults(t *testing.T) {
+	target, err := NewSectionedConfiguration("rules-service")
+	if err != nil {
+		t.Fatalf("NewConfiguration returned error %s", err.Error())
+	}
+	target.Load("./testData/sectionedConfig.json")
+
+	err = target.SetDefaults(map[string]interface{}{
+		"port":            "9999",
+		"dbPoolSize":      10,
+		"timeout":         time.Second * 30,
+		"influx.url":      "http://influxdb:8086",
+		"influx.database": "rules",
+		"tags":            []string{"a", "b"},
+	})
+	if err != nil {
+		t.Fatalf("SetDefaults returned error %s", err.Error())
+	}
+
+	// Section value wins over the default
+	if actual := target.GetStringOr("port", ""); actual != "8085" {
+		t.Errorf("Value for 'port' is incorrect. Expected='%s', Actual='%s'", "8085", actual)
+	}
+
+	actualInt, err := target.GetInt("dbPoolSize")
+	if err != nil || actualInt != 10 {
+		t.Errorf("Default for 'dbPoolSize' is incorrect. Expected='%d', Actual='%d' %v", 10, actualInt, err)
+	}
+
+	actualDuration, err := target.GetDuration("timeout")
+	if err != nil || actualDuration != time.Second*30 {
+		t.Errorf("Default for 'timeout' is incorrect. Expected='%v', Actual='%v' %v", time.Second*30, actualDuration, err)
+	}
+
+	actualSlice, err := target.GetStringSlice("tags")
+	if err != nil || !reflect.DeepEqual([]string{"a", "b"}, actualSlice) {
+		t.Errorf("Default for 'tags' is incorrect. Actual='%v' %v", actualSlice, err)
+	}
+
+	actualMap, err := target.GetNestedJSON("influx")
+	expectedMap := map[string]interface{}{"url": "http://influxdb:8086", "database": "rules"}
+	if err != nil || !reflect.DeepEqual(expectedMap, actualMap) {
+		t.Errorf("Default for 'influx' is incorrect. Expected='%v', Actual='%v' %v", expectedMap, actualMap, err)
+	}
+
+	// Calling again adds to the existing defaults
+	if err := target.SetDefaults(map[string]interface{}{"influx.retries": 3}); err != nil {
+		t.Fatalf("SetDefaults returned error %s", err.Error())
+	}
+
+	if actual := target.GetStringOr("influx.url", ""); actual != "http://influxdb:8086" {
+		t.Errorf("Default for 'influx.url' lost after second SetDefaults: Actual='%s'", actual)
+	}
+
+	if actual := target.GetIntOr("influx.retries", 0); actual != 3 {
+		t.Errorf("Default for 'influx.retries' is incorrect. Expected='%d', Actual='%d'", 3, actual)
+	}
+}
+
+func TestSetDefaultsAfterEnvironment(t *testing.T) {
+	key := "UNIT_TEST_DEFAULTED"
+	target := &Configuration{}
+
+	if err := target.SetDefaults(map[string]interface{}{key: "default"}); err != nil {
+		t.Fatalf("SetDefaults returned error %s", err.Error())
+	}
+
+	if actual := target.GetStringOr(key, ""); actual != "default" {
+		t.Errorf("Default for '%s' is incorrect. Expected='%s', Actual='%s'", key, "default", actual)
+	}
+
+	os.Setenv(key, "environment")
+	defer os.Unsetenv(key)
+
+	if actual := target.GetStringOr(key, ""); actual != "environment" {
+		t.Errorf("Environment for '%s' should win over default. Expected='%s', Actual='%s'", key, "environment", actual)
+	}
+}
+
+func TestSetDefaultsBadValue(t *testing.T) {
+	target := &Configuration{}
+
+	if err := target.SetDefaults(map[string]interface{}{"channel": make(chan bool)}); err == nil {
+		t.Error("expected error for default that can't be converted to JSON")
+	}
+}
+
+func TestGetOrVariants(t *testing.T) {
+	target := loadTypedConfig(t)
+
+	if actual := target.GetStringOr("bogus", "fallback"); actual != "fallback" {
+		t.Errorf("GetStringOr incorrect. Expected='%s', Actual='%s'", "fallback", actual)
+	}
+
+	if actual := target.GetIntOr("bogus", 42); actual != 42 {
+		t.Errorf("GetIntOr incorrect. Expected='%d', Actual='%d'", 42, actual)
+	}
+
+	if actual := target.GetBoolOr("bogus", true); !actual {
+		t.Errorf("GetBoolOr incorrect. Expected='%v', Actual='%v'", true, actual)
+	}
+
+	if actual := target.GetDurationOr("timeout", time.Second); actual != time.Second*90 {
+		t.Errorf("GetDurationOr incorrect. Expected='%v', Actual='%v'", time.Second*90, actual)
+	}
+
+	// Mistyped values also fall back to the default
+	if actual := target.GetDurationOr("badTimeout", time.Second); actual != time.Second {
+		t.Errorf("GetDurationOr incorrect. Expected='%v', Actual='%v'", time.Second, actual)
+	}
+
+	if actual := target.GetIntSliceOr("bogus", []int{1}); !reflect.DeepEqual([]int{1}, actual) {
+		t.Errorf("GetIntSliceOr incorrect. Expected='%v', Actual='%v'", []int{1}, actual)
+	}
+
+	if actual := target.GetByteSizeOr("cacheSize", 0); actual != 10*1000*1000 {
+		t.Errorf("GetByteSizeOr incorrect. Expected='%d', Actual='%d'", 10*1000*1000, actual)
+	}
+}
+
+func TestNotFoundError(t *testing.T) {
+	target := loadTypedConfig(t)
+
+	_, err := target.GetString("bogus")
+	if !isNotFound(err) || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error and got %v", err)
+	}
+
+	_, err = target.GetString("ports")
+	if isNotFound(err) {
+		t.Errorf("expected conversion error and got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// envSeparator joins the prefix, section and key path in an environment variable name
+const envSeparator = "_"
+
+// envName returns the conventional environment variable name for the key path, such as
+// INVENTORY_INFLUX_URL for influx.url with the prefix INVENTORY. Every character other than a letter or
+// digit becomes an underscore, so the section inventory-service is written as INVENTORY_SERVICE, and array
+// indexes are separate words, so readers[2].host is READERS_2_HOST the same as /readers/2/host.
+func envName(prefix string, path string) string {
+	if segments, err := splitPath(path); err == nil {
+		path = strings.Join(segments, envSeparator)
+	}
+
+	name := strings.Map(func(character rune) rune {
+		switch {
+		case character >= 'a' && character <= 'z':
+			return character - 'a' + 'A'
+		case character >= 'A' && character <= 'Z', character >= '0' && character <= '9':
+			return character
+		}
+		return '_'
+	}, path)
+
+	if !strings.HasSuffix(prefix, envSeparator) {
+		prefix += envSeparator
+	}
+
+	return prefix + name
+}
+
+// lookupEnvOverride returns the value of the environment variable overriding the key at path in the section,
+// looking for PREFIX_SECTION_KEY before PREFIX_KEY. Overrides are only read when an EnvPrefix is set, so variables such
+// as PATH or HOME never replace keys of the same name.
+func (config *Configuration) lookupEnvOverride(section string, path string) (value string, name string, found bool) {
+	if config.envPrefix == "" {
+		return "", "", false
+	}
+
+	if section != "" {
+		name = envName(config.envPrefix, sectionPath(section, path))
+		if value, found = os.LookupEnv(name); found {
+			return value, name, true
+		}
+	}
+
+	name = envName(config.envPrefix, path)
+	if value, found = os.LookupEnv(name); found {
+		return value, name, true
+	}
+
+	return "", "", false
+}
+
+// jsonArray returns the items of text when it is a JSON array, such as ["30", "31"]
+func jsonArray(text string) ([]interface{}, bool) {
+	if !strings.HasPrefix(strings.TrimSpace(text), "[") {
+		return nil, false
+	}
+
+	var items []interface{}
+	if err := json.Unmarshal([]byte(text), &items); err != nil {
+		return nil, false
+	}
+
+	return items, true
+}
@@ -0,0 +1,135 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	testCases := []struct {
+		prefix   string
+		path     string
+		expected string
+	}{
+		{"RRP", "influx.url", "RRP_INFLUX_URL"},
+		{"RRP_", "inventory-service.responseLimit", "RRP_INVENTORY_SERVICE_RESPONSELIMIT"},
+		{"rrp", "port", "rrp_PORT"},
+	}
+
+	for _, testCase := range testCases {
+		if actual := envName(testCase.prefix, testCase.path); actual != testCase.expected {
+			t.Errorf("Name not as expected for '%s'. Expected='%s', Actual='%s'", testCase.path, testCase.expected, actual)
+		}
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	environment := map[string]string{
+		"ENV_TEST_PORT":                          "9090",
+		"ENV_TEST_INVENTORY_SERVICE_PORT":        "9091",
+		"ENV_TEST_INFLUX_URL":                    "http://influx-2:8086",
+		"ENV_TEST_EPCFILTERS":                    `["32", "33"]`,
+		"ENV_TEST_WEIGHTS":                       "[0.5, 1.5]",
+		"ENV_TEST_INVENTORY_SERVICE_FACILITIES":  `{"front": {"zone": "sales"}}`,
+		"ENV_TEST_INVENTORY_SERVICE_DEBUG":       "true",
+		"ENV_TEST_INVENTORY_SERVICE_RETRIES":     "5",
+		"ENV_TEST_INVENTORY_SERVICE_EPCPREFIXES": "30,31",
+		"responseLimit":                          "500",
+		"requestTimeout":                         "30",
+	}
+	for name, value := range environment {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	target := &Configuration{sectionName: "inventory-service", envPrefix: "ENV_TEST"}
+	target.processConfigurationChanged([]byte(`{
+		"port": "8080",
+		"influx": {"url": "http://influxdb:8086"},
+		"responseLimit": 100,
+		"inventory-service": {"epcFilters": ["30"], "facilities": {"back": {"zone": "stock"}}, "debug": false}
+	}`))
+
+	// The section's variable takes precedence over the global one
+	expected := Explanation{Path: "port", Value: "9091", Source: EnvironmentSource, Key: "ENV_TEST_INVENTORY_SERVICE_PORT"}
+	if actual, _ := target.Explain("port"); actual != expected {
+		t.Errorf("Explanation not as expected. Expected='%+v', Actual='%+v'", expected, actual)
+	}
+
+	if actual, _ := target.GetString("influx.url"); actual != "http://influx-2:8086" {
+		t.Errorf("influx.url not as expected. Expected='http://influx-2:8086', Actual='%s'", actual)
+	}
+
+	if actual, _ := target.GetStringSlice("epcFilters"); !reflect.DeepEqual(actual, []string{"32", "33"}) {
+		t.Errorf("epcFilters not as expected. Expected='[32 33]', Actual='%v'", actual)
+	}
+
+	if actual, _ := target.GetStringSlice("epcPrefixes"); !reflect.DeepEqual(actual, []string{"30", "31"}) {
+		t.Errorf("epcPrefixes not as expected. Expected='[30 31]', Actual='%v'", actual)
+	}
+
+	if actual, _ := target.GetFloatSlice("weights"); !reflect.DeepEqual(actual, []float64{0.5, 1.5}) {
+		t.Errorf("weights not as expected. Expected='[0.5 1.5]', Actual='%v'", actual)
+	}
+
+	expectedFacilities := map[string]interface{}{"front": map[string]interface{}{"zone": "sales"}}
+	if actual, _ := target.GetNestedJSON("facilities"); !reflect.DeepEqual(actual, expectedFacilities) {
+		t.Errorf("facilities not as expected. Expected='%v', Actual='%v'", expectedFacilities, actual)
+	}
+
+	if actual, _ := target.GetBool("debug"); !actual {
+		t.Error("debug not as expected. Expected='true', Actual='false'")
+	}
+
+	if actual, _ := target.GetInt("retries"); actual != 5 {
+		t.Errorf("retries not as expected. Expected='5', Actual='%d'", actual)
+	}
+
+	// The variable named as the path only fills a gap in the configuration, the same as without a prefix
+	if actual, _ := target.GetInt("responseLimit"); actual != 100 {
+		t.Errorf("responseLimit not as expected. Expected='100', Actual='%d'", actual)
+	}
+
+	expected = Explanation{Path: "requestTimeout", Value: "30", Source: EnvironmentSource, Key: "requestTimeout"}
+	if actual, _ := target.Explain("requestTimeout"); actual != expected {
+		t.Errorf("Explanation not as expected. Expected='%+v', Actual='%+v'", expected, actual)
+	}
+}
+
+func TestEnvOverridesNeedPrefix(t *testing.T) {
+	os.Setenv("_PORT", "9090")
+	defer os.Unsetenv("_PORT")
+	os.Setenv("PORT", "9090")
+	defer os.Unsetenv("PORT")
+
+	target := &Configuration{}
+	target.processConfigurationChanged([]byte(`{"port": "8080"}`))
+
+	if actual, _ := target.GetString("port"); actual != "8080" {
+		t.Errorf("port not as expected without a prefix. Expected='8080', Actual='%s'", actual)
+	}
+}
+
+func TestGetNestedJSONFromEnv(t *testing.T) {
+	os.Setenv("nestedFromEnv", `{"site": "store-2"}`)
+	defer os.Unsetenv("nestedFromEnv")
+
+	target := &Configuration{}
+	target.processConfigurationChanged([]byte(`{"port": "8080"}`))
+
+	actual, err := target.GetNestedJSON("nestedFromEnv")
+	if err != nil {
+		t.Fatalf("GetNestedJSON returned error %s", err.Error())
+	}
+
+	if actual["site"] != "store-2" {
+		t.Errorf("Value not as expected. Expected='store-2', Actual='%v'", actual["site"])
+	}
+}
@@ -0,0 +1,127 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// extendsKey names the section a section inherits its keys from:
+//
+//	"inventory-worker-2": {"extends": "inventory-worker", "port": "8082"}
+const extendsKey = "extends"
+
+// inheritance resolves the sections of one document. Sections are resolved once, and the sections being
+// resolved are tracked to detect cycles.
+type inheritance struct {
+	document  map[string]interface{}
+	resolved  map[string]map[string]interface{}
+	resolving []string
+}
+
+// resolveExtends returns a copy of the document where every section that extends another holds the keys of
+// its base section, and of the base's base, with its own keys taking precedence. The getters and change
+// detection only see the result, so a change to a base section is notified for the sections extending it.
+// Only an error resolving the active section is returned. Other sections belong to other services, so one
+// that can't be resolved is logged and left as written.
+func resolveExtends(parsedJson map[string]interface{}, activeSection string) (map[string]interface{}, error) {
+	extends := &inheritance{
+		document: parsedJson,
+		resolved: make(map[string]map[string]interface{}),
+	}
+
+	var result map[string]interface{}
+	for name, value := range parsedJson {
+		section, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, found := section[extendsKey]; !found {
+			continue
+		}
+
+		resolvedSection, err := extends.resolve(name)
+		if err != nil {
+			if name == activeSection {
+				return nil, err
+			}
+
+			log.Printf("Leaving section '%s' in configuration unresolved: %s", name, err.Error())
+			continue
+		}
+
+		// Only copy the document when a section extends another
+		if result == nil {
+			result = make(map[string]interface{}, len(parsedJson))
+			for key, value := range parsedJson {
+				result[key] = value
+			}
+		}
+		result[name] = resolvedSection
+	}
+
+	if result == nil {
+		return parsedJson, nil
+	}
+
+	return result, nil
+}
+
+func (extends *inheritance) resolve(name string) (map[string]interface{}, error) {
+	if section, done := extends.resolved[name]; done {
+		return section, nil
+	}
+
+	for _, resolving := range extends.resolving {
+		if resolving == name {
+			return nil, fmt.Errorf("'%s' cycle in configuration: %s -> %s", extendsKey, strings.Join(extends.resolving, " -> "), name)
+		}
+	}
+
+	section, ok := extends.document[name].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("section '%s' extended by '%s' not found in configuration", name, extends.lastResolving())
+	}
+
+	baseName, found := section[extendsKey]
+	if !found {
+		return section, nil
+	}
+
+	base, ok := baseName.(string)
+	if !ok {
+		return nil, fmt.Errorf("'%s' of section '%s' must be the name of a section: Value='%v'", extendsKey, name, baseName)
+	}
+
+	extends.resolving = append(extends.resolving, name)
+	baseSection, err := extends.resolve(base)
+	extends.resolving = extends.resolving[:len(extends.resolving)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedSection := make(map[string]interface{}, len(baseSection)+len(section))
+	mergeDocuments(resolvedSection, baseSection)
+	for key, value := range section {
+		if key == extendsKey {
+			continue
+		}
+		mergeDocuments(resolvedSection, map[string]interface{}{key: value})
+	}
+
+	extends.resolved[name] = resolvedSection
+	return resolvedSection, nil
+}
+
+func (extends *inheritance) lastResolving() string {
+	if len(extends.resolving) == 0 {
+		return ""
+	}
+	return extends.resolving[len(extends.resolving)-1]
+}
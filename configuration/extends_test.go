@@ -0,0 +1,114 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const extendsTestJson = `{
+	"loggingLevel": "info",
+	"inventory-worker": {"port": "8080", "responseLimit": 100, "influx": {"database": "inventory", "retries": 3}},
+	"inventory-worker-east": {"extends": "inventory-worker", "region": "east", "influx": {"retries": 5}},
+	"inventory-worker-east-2": {"extends": "inventory-worker-east", "port": "8082"}
+}`
+
+func TestExtends(t *testing.T) {
+	target := &Configuration{sectionName: "inventory-worker-east-2"}
+	target.processConfigurationChanged([]byte(extendsTestJson))
+
+	if actual, _ := target.GetString("port"); actual != "8082" {
+		t.Errorf("port not as expected. Expected='8082', Actual='%s'", actual)
+	}
+
+	if actual, _ := target.GetString("region"); actual != "east" {
+		t.Errorf("region not as expected. Expected='east', Actual='%s'", actual)
+	}
+
+	if actual, _ := target.GetInt("responseLimit"); actual != 100 {
+		t.Errorf("responseLimit not as expected. Expected='100', Actual='%d'", actual)
+	}
+
+	expectedInflux := map[string]interface{}{"database": "inventory", "retries": float64(5)}
+	if actual, _ := target.GetNestedJSON("influx"); !reflect.DeepEqual(expectedInflux, actual) {
+		t.Errorf("influx not as expected. Expected='%v', Actual='%v'", expectedInflux, actual)
+	}
+
+	if _, err := target.GetString("extends"); !isNotFound(err) {
+		t.Errorf("Expected extends to be hidden from the getters, got %v", err)
+	}
+
+	if actual, _ := target.GetString("loggingLevel"); actual != "info" {
+		t.Errorf("loggingLevel not as expected. Expected='info', Actual='%s'", actual)
+	}
+
+	// The base section is left as written
+	base := &Configuration{sectionName: "inventory-worker"}
+	base.processConfigurationChanged([]byte(extendsTestJson))
+	if _, err := base.GetString("region"); !isNotFound(err) {
+		t.Errorf("Expected region not to be in the base section, got %v", err)
+	}
+}
+
+func TestExtendsChanges(t *testing.T) {
+	target := &Configuration{sectionName: "inventory-worker-east-2"}
+	target.processConfigurationChanged([]byte(extendsTestJson))
+
+	var actualChanges []ChangeDetails
+	target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+		actualChanges = changes
+	})
+
+	target.processConfigurationChanged([]byte(strings.Replace(extendsTestJson, `"responseLimit": 100`, `"responseLimit": 200`, 1)))
+
+	expected := []ChangeDetails{{Name: "inventory-worker-east-2.responseLimit", Value: float64(200), OldValue: float64(100), Operation: Updated}}
+	if !reflect.DeepEqual(expected, actualChanges) {
+		t.Errorf("Changes not as expected.\nExpected='%v'\nActual='%v'", expected, actualChanges)
+	}
+}
+
+func TestExtendsErrors(t *testing.T) {
+	testCases := []struct {
+		document string
+		expected string
+	}{
+		{`{"a": {"extends": "b"}, "b": {"extends": "a"}}`, "cycle"},
+		{`{"a": {"extends": "a"}}`, "a -> a"},
+		{`{"a": {"extends": "missing"}}`, "'missing' extended by 'a' not found"},
+		{`{"a": {"extends": "b"}, "b": "value"}`, "'b' extended by 'a' not found"},
+		{`{"a": {"extends": ["b"]}}`, "must be the name of a section"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := resolveExtends(parseTestJson(testCase.document, t), "a")
+		if err == nil || !strings.Contains(err.Error(), testCase.expected) {
+			t.Errorf("Expected error containing '%s' for %s, got %v", testCase.expected, testCase.document, err)
+		}
+	}
+}
+
+func TestExtendsErrorInOtherSection(t *testing.T) {
+	target := &Configuration{sectionName: "inventory-worker"}
+	target.processConfigurationChanged([]byte(`{"inventory-worker": {"port": "8080"}, "other-service": {"extends": "missing"}}`))
+
+	var rejected error
+	target.SetValidationErrorCallback(func(err error) {
+		rejected = err
+	})
+
+	target.processConfigurationChanged([]byte(`{"inventory-worker": {"port": "8081"}, "other-service": {"extends": "missing"}}`))
+
+	if rejected != nil {
+		t.Errorf("Expected the broken section of another service to be ignored, got %v", rejected)
+	}
+
+	if actual, _ := target.GetString("port"); actual != "8081" {
+		t.Errorf("port not as expected. Expected='8081', Actual='%s'", actual)
+	}
+}
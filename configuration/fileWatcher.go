@@ -0,0 +1,203 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+const (
+	filePollInterval = time.Second * 5
+	// fileSettleTime gives editors time to finish writing before the files are read
+	fileSettleTime = time.Millisecond * 100
+)
+
+// FileWatcher reloads the layered local configuration files when any of them change. File system notifications
+// are used where available (inotify on Linux), otherwise the files are polled for changes.
+type FileWatcher struct {
+	sources      []string
+	pollInterval time.Duration
+	polling      bool
+
+	// ctx is canceled by Stop, which waits for done to be closed by the watch goroutine
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// fileState identifies a version of a configuration file without reading it
+type fileState struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func NewFileWatcher(sources []string) (*FileWatcher, error) {
+	return NewFileWatcherWithContext(context.Background(), sources)
+}
+
+// NewFileWatcherWithContext creates a FileWatcher that stops watching when ctx is canceled, as well as when
+// Stop is called
+func NewFileWatcherWithContext(ctx context.Context, sources []string) (*FileWatcher, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("sources can not be empty")
+	}
+
+	watcher := FileWatcher{
+		sources:      sources,
+		pollInterval: filePollInterval,
+	}
+	watcher.ctx, watcher.cancel = context.WithCancel(ctx)
+
+	return &watcher, nil
+}
+
+// Stop stops watching the files and waits for the watch goroutine to exit, including any callback it is
+// running, so it must not be called from the change callback
+func (watcher *FileWatcher) Stop() {
+	watcher.cancel()
+
+	if watcher.done != nil {
+		<-watcher.done
+	}
+}
+
+// Start watches the files in the background and calls changeCallback with the merged document each time
+// the files change. Files that fail to load are logged and skipped until they are changed again.
+func (watcher *FileWatcher) Start(changeCallback func(map[string]interface{})) error {
+	currentState, err := watcher.fileStates()
+	if err != nil {
+		return fmt.Errorf("unable to read configuration files: %s", err.Error())
+	}
+
+	var events <-chan struct{}
+	stopEvents := func() {}
+	if !watcher.polling {
+		directoryEvents, stopDirectoryEvents, err := watchDirectories(watcher.directories())
+		if err != nil {
+			log.Printf("Unable to watch configuration files for changes, polling every %s instead: %s", watcher.pollInterval, err.Error())
+		} else {
+			events, stopEvents = directoryEvents, stopDirectoryEvents
+		}
+	}
+
+	watcher.done = make(chan struct{})
+	go func() {
+		defer close(watcher.done)
+
+		ticker := time.NewTicker(watcher.pollInterval)
+		defer ticker.Stop()
+
+		// A nil channel never receives, so only one of these is ever selected
+		var ticks <-chan time.Time
+		if events == nil {
+			ticks = ticker.C
+		}
+
+		for {
+			select {
+			case _, ok := <-events:
+				if !ok {
+					log.Printf("Configuration file notifications stopped, polling every %s instead", watcher.pollInterval)
+					events = nil
+					ticks = ticker.C
+					continue
+				}
+				time.Sleep(fileSettleTime)
+
+			case <-ticks:
+
+			case <-watcher.ctx.Done():
+				// The notification goroutine closes events once it has exited
+				stopEvents()
+				for events != nil {
+					if _, ok := <-events; !ok {
+						events = nil
+					}
+				}
+				return
+			}
+
+			newState, err := watcher.fileStates()
+			if err != nil {
+				log.Printf("Error checking configuration files for changes: %s", err.Error())
+				continue
+			}
+
+			if reflect.DeepEqual(currentState, newState) {
+				continue
+			}
+			currentState = newState
+
+			parsedJson, err := loadLayers(watcher.sources)
+			if err != nil {
+				log.Printf("Error reloading changed configuration files: %s", err.Error())
+				continue
+			}
+
+			changeCallback(parsedJson)
+		}
+	}()
+
+	return nil
+}
+
+func (watcher *FileWatcher) fileStates() ([]fileState, error) {
+	files, err := expandLayers(watcher.sources)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]fileState, 0, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// Removed since the sources were expanded, which the next check picks up
+				continue
+			}
+			return nil, err
+		}
+		states = append(states, fileState{path: file, size: info.Size(), modTime: info.ModTime()})
+	}
+
+	return states, nil
+}
+
+// directories returns the directories holding the sources. Files are replaced rather than written in place by
+// many editors, so the directory is watched instead of the file. The parent of a directory source is watched as
+// well so the directory being created or removed is noticed.
+func (watcher *FileWatcher) directories() []string {
+	var directories []string
+	seen := make(map[string]bool)
+
+	add := func(directory string) {
+		if !seen[directory] {
+			seen[directory] = true
+			directories = append(directories, directory)
+		}
+	}
+
+	for _, source := range watcher.sources {
+		if info, err := os.Stat(source); err == nil && !info.IsDir() {
+			add(filepath.Dir(source))
+			continue
+		}
+
+		// Directories and sources that do not exist yet
+		add(filepath.Dir(source))
+		add(source)
+	}
+
+	return directories
+}
@@ -0,0 +1,84 @@
+//go:build linux
+// +build linux
+
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// watchDirectories signals on the returned channel when anything changes in the directories. Several changes in
+// quick succession may be signalled once. The channel is closed if the notifications stop, including when they
+// are stopped by the returned function.
+func watchDirectories(directories []string) (<-chan struct{}, func(), error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to initialize inotify: %s", err.Error())
+	}
+
+	// Reading through an os.File uses the runtime poller, so the read does not hold an OS thread
+	file := os.NewFile(uintptr(fd), "inotify")
+
+	if addWatches(fd, directories) == 0 {
+		file.Close()
+		return nil, nil, fmt.Errorf("none of the configuration directories could be watched")
+	}
+
+	events := make(chan struct{}, 1)
+	go func() {
+		defer close(events)
+		defer file.Close()
+
+		buffer := make([]byte, 4096)
+		for {
+			if _, err := file.Read(buffer); err != nil {
+				// Closing the file is how the notifications are stopped
+				if pathErr, ok := err.(*os.PathError); !ok || pathErr.Err != os.ErrClosed {
+					log.Printf("Error reading configuration file notifications: %s", err.Error())
+				}
+				return
+			}
+
+			// Directories that did not exist before may have been created, and replaced ones need a new watch
+			addWatches(fd, directories)
+
+			select {
+			case events <- struct{}{}:
+			default:
+				// A change is already waiting to be handled
+			}
+		}
+	}()
+
+	// Closing the file wakes the read blocked in the runtime poller
+	stop := func() {
+		file.Close()
+	}
+
+	return events, stop, nil
+}
+
+// addWatches watches the directories that exist and returns how many are watched. Adding a watch for a
+// directory that is already watched just updates it.
+func addWatches(fd int, directories []string) int {
+	watched := 0
+	for _, directory := range directories {
+		if _, err := syscall.InotifyAddWatch(fd, directory, inotifyMask); err == nil {
+			watched++
+		}
+	}
+
+	return watched
+}
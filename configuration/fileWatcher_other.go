@@ -0,0 +1,17 @@
+//go:build !linux
+// +build !linux
+
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import "fmt"
+
+// watchDirectories is only implemented on Linux. Other platforms poll the files for changes.
+func watchDirectories(directories []string) (<-chan struct{}, func(), error) {
+	return nil, nil, fmt.Errorf("file system notifications are not supported on this platform")
+}
@@ -0,0 +1,134 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestFileWatcherNotifications(t *testing.T) {
+	verifyFileWatcher(t, false)
+}
+
+func TestFileWatcherPolling(t *testing.T) {
+	verifyFileWatcher(t, true)
+}
+
+// Notifications can't be set up for a directory that doesn't exist, so the watcher falls back to polling
+func TestFileWatcherStopAfterPollingFallback(t *testing.T) {
+	source := filepath.Join(os.TempDir(), "fileWatcher-missing-"+strconv.FormatInt(time.Now().UnixNano(), 10), "config.json")
+
+	watcher, err := NewFileWatcher([]string{source})
+	if err != nil {
+		t.Fatalf("NewFileWatcher returned error %s", err.Error())
+	}
+
+	if err := watcher.Start(func(map[string]interface{}) {}); err != nil {
+		t.Fatalf("Start returned error %s", err.Error())
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		watcher.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for Stop")
+	}
+}
+
+func verifyFileWatcher(t *testing.T, polling bool) {
+	directory, err := ioutil.TempDir("", "fileWatcher")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	basePath := filepath.Join(directory, "configuration.json")
+	writeTestFile(t, basePath, `{"port": "8080", "inventory-service": {"port": "8081"}}`)
+
+	layers := defaultLayers(basePath)
+	target := &Configuration{sectionName: "inventory-service"}
+	if err := target.LoadLayered(layers...); err != nil {
+		t.Fatalf("LoadLayered returned error %s", err.Error())
+	}
+
+	changes := make(chan []ChangeDetails, 10)
+	target.SetConfigChangeCallback(func(changedList []ChangeDetails) {
+		changes <- changedList
+	})
+
+	watcher, err := NewFileWatcher(layers)
+	if err != nil {
+		t.Fatalf("NewFileWatcher returned error %s", err.Error())
+	}
+	watcher.polling = polling
+	watcher.pollInterval = time.Millisecond * 50
+
+	if err := watcher.Start(target.processFilesChanged); err != nil {
+		t.Fatalf("Start returned error %s", err.Error())
+	}
+
+	// Update the base file in place
+	writeTestFile(t, basePath, `{"port": "8080", "inventory-service": {"port": "8085"}}`)
+	verifyFileChange(t, changes, ChangeDetails{Name: "inventory-service.port", Value: "8085", OldValue: "8081", Operation: Updated})
+
+	// Create the overlay directory and a file in it, replacing the file the way editors do
+	overlayDirectory := filepath.Join(directory, overlayDirectoryName)
+	if err := os.Mkdir(overlayDirectory, 0755); err != nil {
+		t.Fatal(err)
+	}
+	temporaryPath := filepath.Join(directory, "overlay.tmp")
+	writeTestFile(t, temporaryPath, "loggingLevel: debug\n")
+	if err := os.Rename(temporaryPath, filepath.Join(overlayDirectory, "10-site.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	verifyFileChange(t, changes, ChangeDetails{Name: "loggingLevel", Value: "debug", Operation: Added})
+
+	if actual, _ := target.GetString("loggingLevel"); actual != "debug" {
+		t.Errorf("loggingLevel not reloaded. Expected='debug', Actual='%s'", actual)
+	}
+
+	// Nothing is reloaded once stopped
+	watcher.Stop()
+	writeTestFile(t, basePath, `{"port": "8080", "inventory-service": {"port": "8086"}}`)
+
+	select {
+	case changedList := <-changes:
+		t.Errorf("Expected no changes after Stop, got %v", changedList)
+	case <-time.After(time.Millisecond * 500):
+	}
+}
+
+func verifyFileChange(t *testing.T, changes chan []ChangeDetails, expected ChangeDetails) {
+	t.Helper()
+
+	select {
+	case changedList := <-changes:
+		if len(changedList) != 1 || changedList[0] != expected {
+			t.Errorf("Changes not as expected. Expected='%v', Actual='%v'", expected, changedList)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("Timed out waiting for change to %s", expected.Name)
+	}
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
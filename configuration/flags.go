@@ -0,0 +1,113 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type boundFlag struct {
+	flagSet *flag.FlagSet
+	name    string
+}
+
+// BindFlags binds a flag to every key of the loaded configuration, the section and global keys by the path
+// the getters use and the defaults. An existing flag named after a path is bound as it is, and a string flag
+// is defined for every other path, with the current value as its default. Call it before the flag set is
+// parsed.
+//
+// A flag set on the command line takes precedence over every other source and is reported by Explain as
+// FlagSource. A flag left unset is ignored, so its default never hides a later change to the configuration.
+func (config *Configuration) BindFlags(flagSet *flag.FlagSet) error {
+	snapshot, _ := config.current.Load().(*configSnapshot)
+	if snapshot == nil {
+		snapshot = &configSnapshot{}
+	}
+
+	paths := make([]string, 0)
+	for path := range config.leafPaths(snapshot.parsedJson) {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if flagSet.Lookup(path) == nil {
+			value, _, _, _ := config.locateIn(snapshot.parsedJson, config.sectionName, path)
+			flagSet.String(path, flagText(config.redactValue(path, value)), fmt.Sprintf("configuration value for '%s'", path))
+		}
+
+		if err := config.BindFlag(flagSet, path, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BindFlag binds the existing flag called name to the key at path, as used by the getters. The flag's value is
+// read as text, the same as an environment variable.
+func (config *Configuration) BindFlag(flagSet *flag.FlagSet, name string, path string) error {
+	if flagSet.Lookup(name) == nil {
+		return fmt.Errorf("unable to bind flag '%s' to '%s': flag is not defined", name, path)
+	}
+
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	if config.flags == nil {
+		config.flags = make(map[string]boundFlag)
+	}
+	config.flags[path] = boundFlag{flagSet: flagSet, name: name}
+
+	return nil
+}
+
+// lookupFlag returns the value of the flag bound to path when it was set on the command line
+func (config *Configuration) lookupFlag(path string) (value string, name string, found bool) {
+	config.mutex.RLock()
+	binding, bound := config.flags[path]
+	config.mutex.RUnlock()
+
+	if !bound {
+		return "", "", false
+	}
+
+	binding.flagSet.Visit(func(setFlag *flag.Flag) {
+		if setFlag.Name == binding.name {
+			value = setFlag.Value.String()
+			found = true
+		}
+	})
+
+	return value, binding.name, found
+}
+
+// flagText writes a value the way it would be given on the command line, with lists as comma separated items
+func flagText(value interface{}) string {
+	switch typedValue := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typedValue
+	case []interface{}:
+		items := make([]string, 0, len(typedValue))
+		for _, item := range typedValue {
+			items = append(items, flagText(item))
+		}
+		return strings.Join(items, ",")
+	}
+
+	text, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(text)
+}
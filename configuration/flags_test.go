@@ -0,0 +1,130 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBindFlags(t *testing.T) {
+	os.Setenv("FLAGS_TEST_port", "9090")
+	defer os.Unsetenv("FLAGS_TEST_port")
+
+	target := &Configuration{sectionName: "inventory-service", envPrefix: "FLAGS_TEST_"}
+	target.processConfigurationChanged([]byte(`{
+		"loggingLevel": "info",
+		"influx": {"url": "http://influxdb:8086", "password": "swordfish"},
+		"inventory-service": {"responseLimit": 100, "epcFilters": ["30", "31"], "debug": false}
+	}`))
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	timeout := flagSet.Duration("timeout", time.Second, "request timeout")
+	if err := target.SetDefaults(map[string]interface{}{"timeout": "15s"}); err != nil {
+		t.Fatalf("SetDefaults returned error %s", err.Error())
+	}
+
+	if err := target.BindFlags(flagSet); err != nil {
+		t.Fatalf("BindFlags returned error %s", err.Error())
+	}
+
+	if err := target.BindFlag(flagSet, "bogus", "port"); err == nil {
+		t.Error("Expected an error binding a flag that is not defined")
+	}
+
+	expectedDefaults := map[string]string{
+		"loggingLevel":    "info",
+		"influx.url":      "http://influxdb:8086",
+		"influx.password": redactedText,
+		"responseLimit":   "100",
+		"epcFilters":      "30,31",
+		"debug":           "false",
+		"timeout":         "1s",
+	}
+	for name, expected := range expectedDefaults {
+		generatedFlag := flagSet.Lookup(name)
+		if generatedFlag == nil {
+			t.Errorf("Expected flag '%s' to be defined", name)
+			continue
+		}
+		if generatedFlag.DefValue != expected {
+			t.Errorf("Default of flag '%s' not as expected. Expected='%s', Actual='%s'", name, expected, generatedFlag.DefValue)
+		}
+	}
+
+	// Flags that are not set are ignored
+	if actual, _ := target.GetString("loggingLevel"); actual != "info" {
+		t.Errorf("loggingLevel not as expected. Expected='info', Actual='%s'", actual)
+	}
+
+	if actual, _ := target.GetDuration("timeout"); actual != time.Second*15 {
+		t.Errorf("timeout not as expected. Expected='15s', Actual='%s'", actual)
+	}
+
+	err := flagSet.Parse([]string{"-loggingLevel=debug", "-responseLimit=500", "-epcFilters=32,33", "-debug=true", "-timeout=2m"})
+	if err != nil {
+		t.Fatalf("Parse returned error %s", err.Error())
+	}
+
+	if actual, _ := target.GetString("loggingLevel"); actual != "debug" {
+		t.Errorf("loggingLevel not as expected. Expected='debug', Actual='%s'", actual)
+	}
+
+	if actual, _ := target.GetInt("responseLimit"); actual != 500 {
+		t.Errorf("responseLimit not as expected. Expected='500', Actual='%d'", actual)
+	}
+
+	if actual, _ := target.GetStringSlice("epcFilters"); !reflect.DeepEqual(actual, []string{"32", "33"}) {
+		t.Errorf("epcFilters not as expected. Expected='[32 33]', Actual='%v'", actual)
+	}
+
+	if actual, _ := target.GetBool("debug"); !actual {
+		t.Error("debug not as expected. Expected='true', Actual='false'")
+	}
+
+	if actual, _ := target.GetDuration("timeout"); actual != *timeout || actual != time.Minute*2 {
+		t.Errorf("timeout not as expected. Expected='2m0s', Actual='%s'", actual)
+	}
+
+	expected := Explanation{Path: "responseLimit", Value: "500", Source: FlagSource, Key: "responseLimit"}
+	if actual, _ := target.Explain("responseLimit"); actual != expected {
+		t.Errorf("Explanation not as expected. Expected='%+v', Actual='%+v'", expected, actual)
+	}
+
+	// A changed document doesn't override a flag
+	target.processConfigurationChanged([]byte(`{"loggingLevel": "error"}`))
+	if actual, _ := target.GetString("loggingLevel"); actual != "debug" {
+		t.Errorf("loggingLevel not as expected after update. Expected='debug', Actual='%s'", actual)
+	}
+}
+
+func TestBindFlag(t *testing.T) {
+	target := &Configuration{}
+	target.processConfigurationChanged([]byte(`{"port": "8080"}`))
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.String("listen-port", "", "port to listen on")
+	if err := target.BindFlag(flagSet, "listen-port", "port"); err != nil {
+		t.Fatalf("BindFlag returned error %s", err.Error())
+	}
+
+	if err := flagSet.Parse([]string{"-listen-port", "8085"}); err != nil {
+		t.Fatalf("Parse returned error %s", err.Error())
+	}
+
+	expected := Explanation{Path: "port", Value: "8085", Source: FlagSource, Key: "listen-port"}
+	if actual, _ := target.Explain("port"); actual != expected {
+		t.Errorf("Explanation not as expected. Expected='%+v', Actual='%+v'", expected, actual)
+	}
+
+	if actual := target.EffectiveConfiguration().Values["port"]; actual != expected {
+		t.Errorf("Effective value not as expected. Expected='%+v', Actual='%+v'", expected, actual)
+	}
+}
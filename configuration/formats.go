@@ -0,0 +1,135 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	jsonFormat = ".json"
+	yamlFormat = ".yaml"
+	ymlFormat  = ".yml"
+	tomlFormat = ".toml"
+)
+
+// configurationFileExtensions lists the supported file types in the order they are searched for
+var configurationFileExtensions = []string{jsonFormat, yamlFormat, ymlFormat, tomlFormat}
+
+// parseDocument parses a configuration document written in JSON, YAML or TOML into the same form encoding/json
+// produces, so getters, sections and change detection behave the same for every format. The format is the file
+// extension, such as ".yaml". An empty or unknown format is detected from the content.
+func parseDocument(content []byte, format string) (map[string]interface{}, error) {
+	switch strings.ToLower(format) {
+	case jsonFormat:
+		return parseJson(content)
+	case yamlFormat, ymlFormat:
+		return parseYaml(content)
+	case tomlFormat:
+		return parseToml(content)
+	}
+
+	// JSON documents always start with an object. Otherwise try TOML before YAML since
+	// YAML would accept most TOML documents as a plain string rather than failing.
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+		return parseJson(content)
+	}
+
+	if parsedJson, err := parseToml(content); err == nil {
+		return parsedJson, nil
+	}
+
+	parsedJson, err := parseYaml(content)
+	if err != nil {
+		return nil, fmt.Errorf("configuration is not valid JSON, YAML or TOML: %s", err.Error())
+	}
+
+	return parsedJson, nil
+}
+
+// findConfigurationFile returns the first configuration.<ext> file found in directory for the supported extensions
+func findConfigurationFile(directory string) (string, bool) {
+	for _, extension := range configurationFileExtensions {
+		filePath := filepath.Join(directory, "configuration"+extension)
+		if _, err := os.Stat(filePath); err == nil {
+			return filePath, true
+		}
+	}
+
+	return "", false
+}
+
+func parseJson(content []byte) (map[string]interface{}, error) {
+	var parsedJson map[string]interface{}
+	if err := json.Unmarshal(content, &parsedJson); err != nil {
+		return nil, err
+	}
+
+	return parsedJson, nil
+}
+
+func parseYaml(content []byte) (map[string]interface{}, error) {
+	var document map[interface{}]interface{}
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+
+	return normalizeDocument(convertYamlValue(document))
+}
+
+func parseToml(content []byte) (map[string]interface{}, error) {
+	var document map[string]interface{}
+	if err := toml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+
+	return normalizeDocument(document)
+}
+
+// convertYamlValue converts the map[interface{}]interface{} objects produced by YAML into map[string]interface{}
+func convertYamlValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(typedValue))
+		for key, item := range typedValue {
+			object[fmt.Sprint(key)] = convertYamlValue(item)
+		}
+		return object
+
+	case []interface{}:
+		items := make([]interface{}, len(typedValue))
+		for index, item := range typedValue {
+			items[index] = convertYamlValue(item)
+		}
+		return items
+	}
+
+	return value
+}
+
+// normalizeDocument converts numbers, times and typed slices into the types encoding/json produces
+func normalizeDocument(document interface{}) (map[string]interface{}, error) {
+	normalized, err := normalizeValue(document)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedJson, ok := normalized.(map[string]interface{})
+	if !ok && normalized != nil {
+		return nil, fmt.Errorf("configuration document must be an object")
+	}
+
+	return parsedJson, nil
+}
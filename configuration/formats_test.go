@@ -0,0 +1,123 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadYamlAndToml(t *testing.T) {
+	for _, file := range []string{"./testData/sectionedConfig.yaml", "./testData/sectionedConfig.toml"} {
+		target := &Configuration{sectionName: "rules-service"}
+		if err := target.Load(file); err != nil {
+			t.Fatalf("Config file %s not loaded: %s", file, err.Error())
+		}
+
+		verifySectionedDocument(target, file, t)
+	}
+}
+
+func TestParseDocumentDetectsFormat(t *testing.T) {
+	expected := map[string]interface{}{"port": "8080", "limit": float64(10), "influx": map[string]interface{}{"url": "http://influxdb:8086"}}
+
+	documents := map[string]string{
+		"json": `{"port": "8080", "limit": 10, "influx": {"url": "http://influxdb:8086"}}`,
+		"yaml": "port: \"8080\"\nlimit: 10\ninflux:\n  url: http://influxdb:8086\n",
+		"toml": "port = \"8080\"\nlimit = 10\n[influx]\nurl = \"http://influxdb:8086\"\n",
+	}
+
+	for format, document := range documents {
+		actual, err := parseDocument([]byte(document), "")
+		if err != nil {
+			t.Errorf("parseDocument returned error for %s content: %s", format, err.Error())
+			continue
+		}
+
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("%s content not parsed as expected. Expected='%v', Actual='%v'", format, expected, actual)
+		}
+	}
+
+	if _, err := parseDocument([]byte("just some text"), ""); err == nil {
+		t.Error("expected error for content that is not a document")
+	}
+}
+
+func TestYamlConsulUpdateChangesDetected(t *testing.T) {
+	target := &Configuration{sectionName: "rules-service"}
+	target.processConfigurationChanged([]byte("port: \"8080\"\nrules-service:\n  port: \"8085\"\n"))
+
+	var actualChanges []ChangeDetails
+	target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+		actualChanges = changes
+	})
+
+	target.processConfigurationChanged([]byte("port: \"8080\"\nrules-service:\n  port: \"9095\"\n"))
+
+	expected := []ChangeDetails{{Name: "rules-service.port", Value: "9095", OldValue: "8085", Operation: Updated}}
+	if !reflect.DeepEqual(expected, actualChanges) {
+		t.Errorf("changes not as expected. Expected='%+v', Actual='%+v'", expected, actualChanges)
+	}
+}
+
+func TestFindConfigurationFile(t *testing.T) {
+	directory, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("unable to create temp directory: %s", err.Error())
+	}
+	defer os.RemoveAll(directory)
+
+	if _, found := findConfigurationFile(directory); found {
+		t.Error("unexpected configuration file found in empty directory")
+	}
+
+	yamlPath := filepath.Join(directory, "configuration.yml")
+	if err := ioutil.WriteFile(yamlPath, []byte("port: \"8080\"\n"), 0644); err != nil {
+		t.Fatalf("unable to write %s: %s", yamlPath, err.Error())
+	}
+
+	actual, found := findConfigurationFile(directory)
+	if !found || actual != yamlPath {
+		t.Errorf("expected to find %s and got '%s'", yamlPath, actual)
+	}
+}
+
+func verifySectionedDocument(target *Configuration, file string, t *testing.T) {
+	if actual, err := target.GetString("port"); err != nil || actual != "8085" {
+		t.Errorf("%s: section port incorrect. Actual='%s' %v", file, actual, err)
+	}
+
+	if actual, err := target.GetInt("responseLimit"); err != nil || actual != 10000 {
+		t.Errorf("%s: global responseLimit incorrect. Actual='%d' %v", file, actual, err)
+	}
+
+	if actual, err := target.GetInt("ruleExecutionPeriodInSec"); err != nil || actual != 300 {
+		t.Errorf("%s: ruleExecutionPeriodInSec incorrect. Actual='%d' %v", file, actual, err)
+	}
+
+	if actual, err := target.GetBool("enabled"); err != nil || !actual {
+		t.Errorf("%s: enabled incorrect. Actual='%v' %v", file, actual, err)
+	}
+
+	if actual, err := target.GetStringSlice("epcFilters"); err != nil || !reflect.DeepEqual([]string{"30", "31"}, actual) {
+		t.Errorf("%s: epcFilters incorrect. Actual='%v' %v", file, actual, err)
+	}
+
+	if actual, err := target.GetNestedJSON("influx"); err != nil || actual["url"] != "http://influxdb:8086" {
+		t.Errorf("%s: influx incorrect. Actual='%v' %v", file, actual, err)
+	}
+
+	expectedTime := time.Date(2019, 6, 1, 10, 30, 0, 0, time.UTC)
+	if actual, err := target.GetTime("startTime"); err != nil || !actual.Equal(expectedTime) {
+		t.Errorf("%s: startTime incorrect. Actual='%v' %v", file, actual, err)
+	}
+}
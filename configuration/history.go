@@ -0,0 +1,193 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/consulApi"
+)
+
+// defaultHistorySize is the number of versions kept when Options.HistorySize is not set
+const defaultHistorySize = 10
+
+// Version is a configuration document as it was read from Consul
+type Version struct {
+	ModifyIndex uint64    `json:"modifyIndex"`
+	Timestamp   time.Time `json:"timestamp"`
+	// Document is the configuration as it was written, before references were expanded. It must not be modified.
+	Document map[string]interface{} `json:"document"`
+}
+
+// History returns the versions of the configuration read from Consul, oldest first. Only the most recent
+// versions are kept, up to Options.HistorySize.
+func (config *Configuration) History() []Version {
+	config.mutex.RLock()
+	defer config.mutex.RUnlock()
+
+	history := make([]Version, len(config.history))
+	copy(history, config.history)
+	return history
+}
+
+// Diff returns the changes from the version with ModifyIndex a to the version with ModifyIndex b, using the
+// full path of each key as its name
+func (config *Configuration) Diff(a uint64, b uint64) ([]ChangeDetails, error) {
+	from, err := config.version(a)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := config.version(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return appendObjectChanges(nil, "", from.Document, to.Document), nil
+}
+
+// Rollback writes the version with ModifyIndex index back to Consul, replacing the current configuration.
+// Like Set, the write is a check-and-set against the last version read, the restored configuration is
+// applied and the callbacks notified before Rollback returns, and Rollback can't be called from a change
+// handler.
+func (config *Configuration) Rollback(index uint64) error {
+	version, err := config.version(index)
+	if err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf("version %d", index)
+	return config.writeBack(target, func(document map[string]interface{}) error {
+		for key := range document {
+			delete(document, key)
+		}
+		mergeDocuments(document, version.Document)
+		return nil
+	}, func(snapshot *configSnapshot, document map[string]interface{}) error {
+		return config.rollbackTree(snapshot, document)
+	})
+}
+
+func (config *Configuration) version(index uint64) (Version, error) {
+	config.mutex.RLock()
+	defer config.mutex.RUnlock()
+
+	for _, version := range config.history {
+		if version.ModifyIndex == index {
+			return version, nil
+		}
+	}
+
+	return Version{}, fmt.Errorf("version %d of the configuration is not in the history", index)
+}
+
+// recordVersion adds the document to the history when it differs from the last version. Callers must hold
+// updateMutex.
+func (config *Configuration) recordVersion(document map[string]interface{}, modifyIndex uint64) {
+	// Local files have no ModifyIndex to roll back to
+	if modifyIndex == 0 {
+		return
+	}
+
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	if count := len(config.history); count > 0 {
+		last := config.history[count-1]
+		// The same version is read again after a write back, and a key tree's index also moves for unrelated keys
+		if last.ModifyIndex >= modifyIndex || reflect.DeepEqual(last.Document, document) {
+			return
+		}
+	}
+
+	config.history = append(config.history, Version{ModifyIndex: modifyIndex, Timestamp: time.Now(), Document: document})
+
+	historySize := config.historySize
+	if historySize <= 0 {
+		historySize = defaultHistorySize
+	}
+	if len(config.history) > historySize {
+		config.history = append([]Version(nil), config.history[len(config.history)-historySize:]...)
+	}
+}
+
+// rollbackTree writes the keys of document that differ from the key tree and deletes the keys not in it.
+// Every key is checked-and-set, so a key changed by someone else stops the rollback with a *ConflictError,
+// although the keys written before it are kept.
+func (config *Configuration) rollbackTree(snapshot *configSnapshot, document map[string]interface{}) error {
+	prefix := treePrefix(config.consulKey)
+	keyValuePairs, _, err := config.consul.ListValues(prefix, nil)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[string]*consulApi.KeyValuePair, len(keyValuePairs))
+	for _, keyValuePair := range keyValuePairs {
+		if keyValuePair.ModifyIndex > snapshot.modifyIndex {
+			return &ConflictError{Key: keyValuePair.Key, ModifyIndex: snapshot.modifyIndex}
+		}
+		current[keyValuePair.Key] = keyValuePair
+	}
+
+	keys, err := treeFromDocument(prefix, document)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+
+	for _, key := range names {
+		var modifyIndex uint64
+		if keyValuePair, found := current[key]; found {
+			delete(current, key)
+			if equalJson(keyValuePair.Value, keys[key]) {
+				continue
+			}
+			modifyIndex = keyValuePair.ModifyIndex
+		}
+
+		ok, err := config.consul.PutValueCAS(key, keys[key], modifyIndex)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &ConflictError{Key: key, ModifyIndex: snapshot.modifyIndex}
+		}
+	}
+
+	for key, keyValuePair := range current {
+		ok, err := config.consul.DeleteValueCAS(key, keyValuePair.ModifyIndex)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &ConflictError{Key: key, ModifyIndex: snapshot.modifyIndex}
+		}
+	}
+
+	return nil
+}
+
+// equalJson returns whether the stored value decodes to the same value as valueJson
+func equalJson(stored []byte, valueJson string) bool {
+	var storedValue, value interface{}
+	if json.Unmarshal(stored, &storedValue) != nil {
+		return false
+	}
+	if json.Unmarshal([]byte(valueJson), &value) != nil {
+		return false
+	}
+	return reflect.DeepEqual(storedValue, value)
+}
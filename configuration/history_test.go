@@ -0,0 +1,106 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHistoryAndRollback(t *testing.T) {
+	for _, tree := range []bool{false, true} {
+		target, _, _ := newWriteBackConfig(t, tree)
+
+		if err := target.Set("inventory-service.serviceName", "Site Inventory Service"); err != nil {
+			t.Fatalf("Set returned error %s", err.Error())
+		}
+		if err := target.Delete("inventory-service.debugEndpoint"); err != nil {
+			t.Fatalf("Delete returned error %s", err.Error())
+		}
+
+		history := target.History()
+		if len(history) != 3 {
+			t.Fatalf("Expected 3 versions in the history, got %d", len(history))
+		}
+
+		first := history[0].ModifyIndex
+		last := history[2].ModifyIndex
+		if first >= last || history[0].Timestamp.After(history[2].Timestamp) {
+			t.Errorf("Expected versions oldest first, got %v and %v", history[0], history[2])
+		}
+
+		actualDiff, err := target.Diff(first, last)
+		if err != nil {
+			t.Fatalf("Diff returned error %s", err.Error())
+		}
+
+		expectedDiff := []ChangeDetails{
+			{Name: "inventory-service.debugEndpoint", OldValue: "/debug", Operation: Deleted},
+			{Name: "inventory-service.serviceName", Value: "Site Inventory Service", OldValue: "RRP Inventory Service", Operation: Updated},
+		}
+		if !reflect.DeepEqual(expectedDiff, actualDiff) {
+			t.Errorf("Diff not as expected.\nExpected='%v'\nActual='%v'", expectedDiff, actualDiff)
+		}
+
+		var actualChanges []ChangeDetails
+		target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+			actualChanges = changes
+		})
+
+		if err := target.Rollback(first); err != nil {
+			t.Fatalf("Rollback returned error %s", err.Error())
+		}
+
+		if actual, _ := target.GetString("serviceName"); actual != "RRP Inventory Service" {
+			t.Errorf("serviceName not rolled back. Expected='RRP Inventory Service', Actual='%s'", actual)
+		}
+
+		if actual, _ := target.GetString("debugEndpoint"); actual != "/debug" {
+			t.Errorf("debugEndpoint not rolled back. Expected='/debug', Actual='%s'", actual)
+		}
+
+		if len(actualChanges) != 2 {
+			t.Errorf("Expected change notifications for the rollback, got %v", actualChanges)
+		}
+
+		// The rollback is a new version of its own
+		history = target.History()
+		if len(history) != 4 || !reflect.DeepEqual(history[0].Document, history[3].Document) {
+			t.Errorf("Expected rolled back version at the end of the history, got %v", history)
+		}
+
+		// Consul's index only increases, so an index far past the versions written here isn't in the history
+		missing := last + 1000
+		if _, err := target.Diff(first, missing); err == nil {
+			t.Error("Expected an error for Diff with a version not in the history")
+		}
+
+		if err := target.Rollback(missing); err == nil {
+			t.Error("Expected an error for Rollback to a version not in the history")
+		}
+	}
+}
+
+func TestHistorySize(t *testing.T) {
+	target, _, _ := newWriteBackConfig(t, false)
+	target.historySize = 2
+
+	for _, port := range []string{"8081", "8082", "8083"} {
+		if err := target.Set("port", port); err != nil {
+			t.Fatalf("Set returned error %s", err.Error())
+		}
+	}
+
+	history := target.History()
+	if len(history) != 2 {
+		t.Fatalf("Expected the history to be bounded to 2 versions, got %d", len(history))
+	}
+
+	if history[1].Document["port"] != "8083" || history[0].Document["port"] != "8082" {
+		t.Errorf("Expected the most recent versions to be kept, got %v", history)
+	}
+}
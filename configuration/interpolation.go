@@ -0,0 +1,243 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	envReference       = "env:"
+	fileReference      = "file:"
+	envDefaultOperator = ":-"
+)
+
+// referencePattern matches a ${...} expression in a string value
+var referencePattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// prepareDocument applies the active profile to a newly loaded or updated document, resolves the sections
+// extending others, expands its references and validates the result
+func (config *Configuration) prepareDocument(parsedJson map[string]interface{}) (map[string]interface{}, error) {
+	parsedJson, err := config.applyProfile(parsedJson)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedJson, err = resolveExtends(parsedJson, config.sectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedJson, err = interpolateDocument(parsedJson, config.sectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.validate(parsedJson); err != nil {
+		return nil, err
+	}
+
+	return parsedJson, nil
+}
+
+// interpolation expands the references in one document. References to other keys are expanded once, and the
+// keys being expanded are tracked to detect cycles.
+type interpolation struct {
+	document  map[string]interface{}
+	section   string
+	expanded  map[string]interface{}
+	expanding []string
+}
+
+// interpolateDocument returns a copy of the document with the expressions in string values expanded:
+//
+//	${env:NAME}            the NAME environment variable, which must be set
+//	${env:NAME:-default}   the NAME environment variable, or default when it is not set or empty
+//	${other.key.path}      the value of another key, looked up in the section first and then the global keys
+//
+// A string that is a single reference to another key takes the type of the value it refers to, so
+// "${limits.retries}" can be a number. ${file:...} secret references are left for the getters to resolve.
+//
+// An error in the global values or the section, or in a key they refer to, is returned. Any other object may
+// be another service's section, expanded with references this process can't resolve, so it is left as written
+// when it fails to expand rather than failing the whole document.
+func interpolateDocument(document map[string]interface{}, section string) (map[string]interface{}, error) {
+	if document == nil {
+		return nil, nil
+	}
+
+	interpolation := &interpolation{
+		document: document,
+		section:  section,
+		expanded: make(map[string]interface{}),
+	}
+
+	expandedDocument := make(map[string]interface{}, len(document))
+	for key, value := range document {
+		expanded, err := interpolation.expandPath(joinPath("", key), value)
+		if err != nil {
+			if _, isObject := value.(map[string]interface{}); !isObject || key == section {
+				return nil, err
+			}
+
+			log.Printf("Leaving '%s' in configuration unexpanded: %s", key, err.Error())
+			expanded = value
+		}
+		expandedDocument[key] = expanded
+	}
+
+	return expandedDocument, nil
+}
+
+// expandPath expands the value at the document path, using the earlier result if it was already expanded
+func (interpolation *interpolation) expandPath(path string, value interface{}) (interface{}, error) {
+	if expanded, ok := interpolation.expanded[path]; ok {
+		return expanded, nil
+	}
+
+	for index, expanding := range interpolation.expanding {
+		if expanding == path {
+			cycle := append(append([]string(nil), interpolation.expanding[index:]...), path)
+			return nil, fmt.Errorf("reference cycle in configuration: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	interpolation.expanding = append(interpolation.expanding, path)
+	expanded, err := interpolation.expandValue(path, value)
+	interpolation.expanding = interpolation.expanding[:len(interpolation.expanding)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	interpolation.expanded[path] = expanded
+	return expanded, nil
+}
+
+func (interpolation *interpolation) expandValue(path string, value interface{}) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case string:
+		return interpolation.expandString(path, typedValue)
+
+	case map[string]interface{}:
+		expandedMap := make(map[string]interface{}, len(typedValue))
+		for key, item := range typedValue {
+			expandedItem, err := interpolation.expandPath(joinPath(path, key), item)
+			if err != nil {
+				return nil, err
+			}
+			expandedMap[key] = expandedItem
+		}
+		return expandedMap, nil
+
+	case []interface{}:
+		expandedSlice := make([]interface{}, len(typedValue))
+		for index, item := range typedValue {
+			// Items are tracked by path like the keys of an object, since a reference can name an item
+			expandedItem, err := interpolation.expandPath(indexPath(path, index), item)
+			if err != nil {
+				return nil, err
+			}
+			expandedSlice[index] = expandedItem
+		}
+		return expandedSlice, nil
+	}
+
+	return value, nil
+}
+
+func (interpolation *interpolation) expandString(path string, value string) (interface{}, error) {
+	if !strings.Contains(value, "${") {
+		return value, nil
+	}
+
+	// A single reference to another key keeps the type of the value referred to
+	if match := referencePattern.FindStringSubmatchIndex(value); match != nil && match[0] == 0 && match[1] == len(value) {
+		return interpolation.expandReference(path, value[match[2]:match[3]])
+	}
+
+	var expandErr error
+	expanded := referencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		if expandErr != nil {
+			return reference
+		}
+
+		referenceValue, err := interpolation.expandReference(path, referencePattern.FindStringSubmatch(reference)[1])
+		if err != nil {
+			expandErr = err
+			return reference
+		}
+
+		text, err := referenceText(referenceValue)
+		if err != nil {
+			expandErr = fmt.Errorf("unable to expand '%s' in '%s': %s", reference, path, err.Error())
+		}
+		return text
+	})
+
+	if expandErr != nil {
+		return nil, expandErr
+	}
+
+	return expanded, nil
+}
+
+func (interpolation *interpolation) expandReference(path string, expression string) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(expression, fileReference):
+		// Secrets are resolved when read so they are never held in the document
+		return "${" + expression + "}", nil
+
+	case strings.HasPrefix(expression, envReference):
+		name := strings.TrimPrefix(expression, envReference)
+		defaultValue, hasDefault := "", false
+		if index := strings.Index(name, envDefaultOperator); index >= 0 {
+			name, defaultValue, hasDefault = name[:index], name[index+len(envDefaultOperator):], true
+		}
+
+		value, ok := os.LookupEnv(name)
+		if hasDefault && value == "" {
+			return defaultValue, nil
+		}
+		if !ok {
+			return nil, fmt.Errorf("unresolved reference '${%s}' in '%s': environment variable %s is not set", expression, path, name)
+		}
+		return value, nil
+	}
+
+	var candidates []string
+	if interpolation.section != "" {
+		candidates = append(candidates, sectionPath(interpolation.section, expression))
+	}
+	candidates = append(candidates, expression)
+
+	for _, candidate := range candidates {
+		if value, found := lookupPath(interpolation.document, candidate); found {
+			return interpolation.expandPath(candidate, value)
+		}
+	}
+
+	return nil, fmt.Errorf("unresolved reference '${%s}' in '%s': key not found", expression, path)
+}
+
+// referenceText formats a value that is expanded within a longer string
+func referenceText(value interface{}) (string, error) {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue, nil
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(typedValue), nil
+	}
+
+	return "", fmt.Errorf("objects and arrays can't be embedded in a string")
+}
@@ -0,0 +1,144 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterpolateDocument(t *testing.T) {
+	os.Setenv("INTERPOLATION_HOST", "edge-gateway")
+	defer os.Unsetenv("INTERPOLATION_HOST")
+
+	document := parseTestJson(`{
+		"host": "${env:INTERPOLATION_HOST}",
+		"influxPort": 8086,
+		"influxUrl": "http://${host}:${influxPort}",
+		"retries": "${limits.retries}",
+		"limits": {"retries": 3, "timeout": "${env:INTERPOLATION_TIMEOUT:-30s}"},
+		"urls": ["${influxUrl}/write", "${env:INTERPOLATION_MISSING:-}"],
+		"writeUrl": "${urls[0]}",
+		"password": "${file:/run/secrets/influx}",
+		"inventory-service": {
+			"host": "inventory",
+			"serviceUrl": "http://${host}:8080"
+		}
+	}`, t)
+
+	actual, err := interpolateDocument(document, "inventory-service")
+	if err != nil {
+		t.Fatalf("interpolateDocument returned error %s", err.Error())
+	}
+
+	expected := parseTestJson(`{
+		"host": "edge-gateway",
+		"influxPort": 8086,
+		"influxUrl": "http://inventory:8086",
+		"retries": 3,
+		"limits": {"retries": 3, "timeout": "30s"},
+		"urls": ["http://inventory:8086/write", ""],
+		"writeUrl": "http://inventory:8086/write",
+		"password": "${file:/run/secrets/influx}",
+		"inventory-service": {
+			"host": "inventory",
+			"serviceUrl": "http://inventory:8080"
+		}
+	}`, t)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Document not interpolated as expected.\nExpected='%v'\nActual='%v'", expected, actual)
+	}
+}
+
+func TestInterpolateDocumentErrors(t *testing.T) {
+	testCases := []struct {
+		document string
+		expected string
+	}{
+		{`{"a": "${b}", "b": "x${a}"}`, "reference cycle"},
+		{`{"self": "${self}"}`, "self -> self"},
+		{`{"value": "${missing.key}"}`, "'${missing.key}'"},
+		{`{"value": "${env:INTERPOLATION_UNSET_VARIABLE}"}`, "INTERPOLATION_UNSET_VARIABLE"},
+		{`{"object": {"a": 1}, "value": "x${object}"}`, "can't be embedded"},
+		{`{"readers": [{"host": "${readers[0].host}"}]}`, "readers[0].host -> readers[0].host"},
+		{`{"hosts.v2": ["${missing}"]}`, "in '/hosts.v2/0'"},
+	}
+
+	for _, testCase := range testCases {
+		_, err := interpolateDocument(parseTestJson(testCase.document, t), "")
+		if err == nil || !strings.Contains(err.Error(), testCase.expected) {
+			t.Errorf("Expected error containing '%s' for %s, got %v", testCase.expected, testCase.document, err)
+		}
+	}
+}
+
+func TestInterpolateOtherSections(t *testing.T) {
+	document := parseTestJson(`{
+		"host": "influx-1",
+		"influx": {"url": "http://${host}:8086"},
+		"inventory-service": {"influxUrl": "${influx.url}"},
+		"other-service": {"token": "${env:INTERPOLATION_UNSET_VARIABLE}", "url": "${influx.url}"}
+	}`, t)
+
+	actual, err := interpolateDocument(document, "inventory-service")
+	if err != nil {
+		t.Fatalf("interpolateDocument returned error %s", err.Error())
+	}
+
+	if value, _ := lookupPath(actual, "inventory-service.influxUrl"); value != "http://influx-1:8086" {
+		t.Errorf("inventory-service.influxUrl not as expected. Expected='http://influx-1:8086', Actual='%v'", value)
+	}
+
+	// The section that can't be expanded in this process is left as written
+	if value, _ := lookupPath(actual, "other-service.url"); value != "${influx.url}" {
+		t.Errorf("other-service.url not as expected. Expected='${influx.url}', Actual='%v'", value)
+	}
+
+	// The same reference in the active section is still an error
+	document["inventory-service"] = map[string]interface{}{"token": "${env:INTERPOLATION_UNSET_VARIABLE}"}
+	if _, err := interpolateDocument(document, "inventory-service"); err == nil {
+		t.Error("Expected an error for the unset variable in the active section")
+	}
+}
+
+func TestInterpolationOnConsulUpdate(t *testing.T) {
+	target := &Configuration{sectionName: "inventory-service"}
+	target.processConfigurationChanged([]byte(`{"host": "influx-1", "influxUrl": "http://${host}:8086"}`))
+
+	var actualChanges []ChangeDetails
+	target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+		actualChanges = changes
+	})
+
+	var rejected error
+	target.SetValidationErrorCallback(func(err error) {
+		rejected = err
+	})
+
+	target.processConfigurationChanged([]byte(`{"host": "influx-2", "influxUrl": "http://${host}:8086"}`))
+
+	expected := []ChangeDetails{
+		{Name: "host", Value: "influx-2", OldValue: "influx-1", Operation: Updated},
+		{Name: "influxUrl", Value: "http://influx-2:8086", OldValue: "http://influx-1:8086", Operation: Updated},
+	}
+	if !reflect.DeepEqual(expected, actualChanges) {
+		t.Errorf("Changes not as expected.\nExpected='%v'\nActual='%v'", expected, actualChanges)
+	}
+
+	// An update with a cycle is rejected and the last good configuration kept
+	target.processConfigurationChanged([]byte(`{"host": "${influxUrl}", "influxUrl": "http://${host}:8086"}`))
+	if rejected == nil || !strings.Contains(rejected.Error(), "cycle") {
+		t.Errorf("Expected update with reference cycle to be rejected, got %v", rejected)
+	}
+
+	if actual, _ := target.GetString("influxUrl"); actual != "http://influx-2:8086" {
+		t.Errorf("influxUrl not as expected. Expected='http://influx-2:8086', Actual='%s'", actual)
+	}
+}
@@ -0,0 +1,149 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// overlayDirectoryName is the directory next to the base configuration file holding local overrides
+	overlayDirectoryName = "configuration.d"
+	secretsDirectory     = "/run/secrets"
+)
+
+// LoadLayered loads the configuration from an ordered list of sources and deep merges each one over the
+// sources before it. Objects are merged key by key, while any other value, including arrays, replaces the
+// value beneath it. A null value removes the key from the merged document.
+//
+// A source that is a directory expands to every supported configuration file in it, in lexical order of
+// the file names. Sources that do not exist are skipped, but at least one file must be found.
+func (config *Configuration) LoadLayered(sources ...string) error {
+	parsedJson, err := loadLayers(sources)
+	if err != nil {
+		return err
+	}
+
+	document := parsedJson
+	parsedJson, err = config.prepareDocument(document)
+	if err != nil {
+		return err
+	}
+
+	config.updateMutex.Lock()
+	defer config.updateMutex.Unlock()
+
+	config.setParsedJson(parsedJson, document, 0)
+	return nil
+}
+
+// defaultLayers returns the sources layered over the base configuration file: every file in the
+// configuration.d directory next to it, then the secrets file when it is not already the base file.
+func defaultLayers(basePath string) []string {
+	if basePath == "" {
+		return nil
+	}
+
+	layers := []string{basePath, filepath.Join(filepath.Dir(basePath), overlayDirectoryName)}
+
+	if secretsPath, found := findConfigurationFile(secretsDirectory); found && secretsPath != basePath {
+		layers = append(layers, secretsPath)
+	}
+
+	return layers
+}
+
+// loadLayers parses and merges the layer files in order into a new document
+func loadLayers(sources []string) (map[string]interface{}, error) {
+	files, err := expandLayers(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no configuration files found in %s", strings.Join(sources, ", "))
+	}
+
+	parsedJson := make(map[string]interface{})
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		layer, err := parseDocument(content, filepath.Ext(file))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse configuration file %s: %s", file, err.Error())
+		}
+
+		mergeDocuments(parsedJson, layer)
+	}
+
+	removeNullValues(parsedJson)
+	return parsedJson, nil
+}
+
+// expandLayers replaces each directory with the configuration files in it and drops sources that do not exist
+func expandLayers(sources []string) ([]string, error) {
+	var files []string
+	for _, source := range sources {
+		info, err := os.Stat(source)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			files = append(files, source)
+			continue
+		}
+
+		// ReadDir returns the entries sorted by file name
+		entries, err := ioutil.ReadDir(source)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || !isConfigurationFile(entry.Name()) {
+				continue
+			}
+			files = append(files, filepath.Join(source, entry.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+func isConfigurationFile(name string) bool {
+	extension := strings.ToLower(filepath.Ext(name))
+	for _, supported := range configurationFileExtensions {
+		if extension == supported {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeNullValues deletes the keys a later layer set to null so they are no longer part of the document
+func removeNullValues(parsedJson map[string]interface{}) {
+	for key, value := range parsedJson {
+		switch typedValue := value.(type) {
+		case nil:
+			delete(parsedJson, key)
+		case map[string]interface{}:
+			removeNullValues(typedValue)
+		}
+	}
+}
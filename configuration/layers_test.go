@@ -0,0 +1,108 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadLayered(t *testing.T) {
+	target := &Configuration{sectionName: "inventory-service"}
+	err := target.LoadLayered("./testData/layered/base.json", "./testData/layered/configuration.d", "./testData/layered/secrets.toml")
+	if err != nil {
+		t.Fatalf("LoadLayered returned error %s", err.Error())
+	}
+
+	// The last layer setting a key wins
+	if actual, _ := target.GetString("loggingLevel"); actual != "warn" {
+		t.Errorf("loggingLevel not as expected. Expected='warn', Actual='%s'", actual)
+	}
+
+	// Objects are merged key by key
+	expectedInflux := map[string]interface{}{
+		"url":      "http://influxdb:8086",
+		"database": "site-inventory",
+		"retries":  float64(3),
+		"password": "secret",
+	}
+	if actual, err := target.GetNestedJSON("influx"); err != nil || !reflect.DeepEqual(expectedInflux, actual) {
+		t.Errorf("influx not merged as expected. Expected='%v', Actual='%v', Error='%v'", expectedInflux, actual, err)
+	}
+
+	// Arrays replace the array beneath them
+	if actual, _ := target.GetStringSlice("epcFilters"); !reflect.DeepEqual([]string{"32"}, actual) {
+		t.Errorf("epcFilters not as expected. Expected='[32]', Actual='%v'", actual)
+	}
+
+	// Null removes the key
+	if _, err := target.GetString("debugEndpoint"); err == nil {
+		t.Error("Expected debugEndpoint to be removed by a null layer value")
+	}
+
+	if actual, _ := target.GetString("serviceName"); actual != "RRP Inventory Service" {
+		t.Errorf("serviceName not as expected. Expected='RRP Inventory Service', Actual='%s'", actual)
+	}
+}
+
+func TestLoadLayeredMissingSources(t *testing.T) {
+	target := &Configuration{}
+	if err := target.LoadLayered("./testData/layered/base.json", "./testData/layered/missing.d"); err != nil {
+		t.Fatalf("LoadLayered returned error for missing optional layer: %s", err.Error())
+	}
+
+	if actual, _ := target.GetString("loggingLevel"); actual != "info" {
+		t.Errorf("loggingLevel not as expected. Expected='info', Actual='%s'", actual)
+	}
+
+	if err := target.LoadLayered("./testData/layered/missing.json"); err == nil {
+		t.Error("Expected error when no configuration files are found")
+	}
+}
+
+func TestLoadLayeredBadLayer(t *testing.T) {
+	directory, err := ioutil.TempDir("", "layers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	badFile := filepath.Join(directory, "bad.json")
+	if err := ioutil.WriteFile(badFile, []byte("{ not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	target := &Configuration{}
+	target.LoadLayered("./testData/layered/base.json")
+
+	err = target.LoadLayered("./testData/layered/base.json", directory)
+	if err == nil || !strings.Contains(err.Error(), "bad.json") {
+		t.Errorf("Expected error naming the bad layer file, got %v", err)
+	}
+
+	// The previous configuration is kept
+	if actual, _ := target.GetString("port"); actual != "8080" {
+		t.Errorf("port not as expected. Expected='8080', Actual='%s'", actual)
+	}
+}
+
+func TestDefaultLayers(t *testing.T) {
+	basePath := filepath.Join("testData", "layered", "base.json")
+	layers := defaultLayers(basePath)
+
+	if len(layers) < 2 || layers[0] != basePath || layers[1] != filepath.Join("testData", "layered", overlayDirectoryName) {
+		t.Errorf("default layers not as expected: %v", layers)
+	}
+
+	if layers := defaultLayers(""); layers != nil {
+		t.Errorf("Expected no layers without a base file, got %v", layers)
+	}
+}
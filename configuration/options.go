@@ -0,0 +1,188 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strconv"
+)
+
+// Options lists every source of a Configuration for NewConfigurationWithOptions. Nothing is read from the
+// environment or inferred from the caller, other than the environment variable fallbacks of the getters.
+type Options struct {
+	// SectionName is the section whose keys take precedence over the global keys. Sections are named like
+	// services, such as "inventory-service", and changes to the sections of other services aren't notified.
+	SectionName string
+
+	// FilePaths are the local configuration sources, merged in order as by LoadLayered
+	FilePaths []string
+
+	// ConsulAddress and ConsulKey select the Consul service and key holding the configuration. When both are
+	// set the configuration is read from and watched in Consul, and the merged FilePaths are pushed to the
+	// key if it does not exist yet.
+	ConsulAddress string
+	ConsulKey     string
+
+	// ConsulTree treats ConsulKey as a prefix. Every key beneath it is a node of the configuration, so
+	// config/inventory/influx/url holds influx.url, and each key can be edited on its own.
+	ConsulTree bool
+
+	// EnvPrefix names the environment variables that override keys, such as PREFIX_SECTION_INFLUX_URL for
+	// influx.url in the section or PREFIX_INFLUX_URL for the key wherever it is. These take precedence over
+	// the configuration, and a value may be JSON for lists and objects. A key missing from the configuration
+	// is still read from the environment variable named as its path, such as influx.url, with or without it.
+	EnvPrefix string
+
+	// Schema is a JSON schema document, and SchemaPath the path to one, the configuration is validated against
+	Schema     string
+	SchemaPath string
+
+	// Defaults are the values used for keys missing from the configuration, as set by SetDefaults
+	Defaults map[string]interface{}
+
+	// CacheFile is where the last configuration read from Consul is saved. When Consul can't be reached at
+	// startup the saved configuration is used instead, with a warning, and replaced by the configuration in
+	// Consul, notifying the callbacks of the differences, once Consul is reachable again.
+	CacheFile string
+
+	// HistorySize is the number of versions read from Consul kept for History, Diff and Rollback. It
+	// defaults to 10.
+	HistorySize int
+
+	// Profile selects the block of the document's profiles overlaid on the global keys and sections, such as
+	// "prod" for "profiles": {"prod": {...}}
+	Profile string
+
+	// WatchFiles reloads the local files when they change. It only applies when Consul is not used.
+	WatchFiles bool
+}
+
+// NewConfigurationWithOptions creates a Configuration from the sources in options
+func NewConfigurationWithOptions(options Options) (*Configuration, error) {
+	return NewConfigurationWithContext(context.Background(), options)
+}
+
+// NewConfigurationWithContext creates a Configuration from the sources in options that stops watching them for
+// changes when ctx is canceled, the same as Close
+func NewConfigurationWithContext(ctx context.Context, options Options) (*Configuration, error) {
+	config := Configuration{
+		sectionName: options.SectionName,
+		envPrefix:   options.EnvPrefix,
+		historySize: options.HistorySize,
+		profile:     options.Profile,
+		cachePath:   options.CacheFile,
+	}
+
+	ctx, config.cancel = context.WithCancel(ctx)
+	err := config.load(ctx, options)
+	if err != nil {
+		config.Close()
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// optionsFromEnvironment returns the options used by NewConfiguration and NewSectionedConfiguration. The local
+// configuration file is searched for in the caller's source directory, then at runtimeConfigPath and then in
+// /run/secrets, and Consul is set with the consulUrl and consulConfigKey environment variables. Setting
+// consulConfigTree to true reads the configuration from the keys beneath consulConfigKey, configEnvPrefix
+// sets the EnvPrefix of the environment variable overrides, configProfile selects the active profile and
+// configCachePath sets the CacheFile.
+func optionsFromEnvironment(callerPath string) Options {
+	options := Options{
+		ConsulAddress: os.Getenv("consulUrl"),
+		ConsulKey:     os.Getenv("consulConfigKey"),
+		SchemaPath:    os.Getenv("configSchemaPath"),
+		EnvPrefix:     os.Getenv("configEnvPrefix"),
+		Profile:       os.Getenv("configProfile"),
+		CacheFile:     os.Getenv("configCachePath"),
+		WatchFiles:    true,
+	}
+
+	if tree, err := strconv.ParseBool(os.Getenv("consulConfigTree")); err == nil {
+		options.ConsulTree = tree
+	}
+
+	if callerPath == "" {
+		log.Print("No caller information")
+	}
+
+	// By default load local configuration file, which may be JSON, YAML or TOML, if it exists
+	absolutePath, found := findConfigurationFile(path.Dir(callerPath))
+	if !found {
+		var ok bool
+		absolutePath, ok = os.LookupEnv("runtimeConfigPath")
+		if !ok {
+			absolutePath, _ = findConfigurationFile(secretsDirectory)
+		}
+		if _, err := os.Stat(absolutePath); err != nil {
+			absolutePath = ""
+		}
+	}
+
+	// Local overrides in configuration.d and the secrets file are merged over the base file
+	options.FilePaths = defaultLayers(absolutePath)
+
+	return options
+}
+
+func (config *Configuration) load(ctx context.Context, options Options) error {
+	// The schema must be in place before the first document is loaded so it is enforced on the initial load
+	if options.Schema != "" {
+		if err := config.SetSchema(options.Schema); err != nil {
+			return err
+		}
+	}
+
+	if options.SchemaPath != "" {
+		if err := config.SetSchemaFile(options.SchemaPath); err != nil {
+			return err
+		}
+	}
+
+	if options.Defaults != nil {
+		if err := config.SetDefaults(options.Defaults); err != nil {
+			return err
+		}
+	}
+
+	if options.ConsulAddress != "" && options.ConsulKey != "" {
+		return config.loadFromConsul(ctx, options.FilePaths, options.ConsulAddress, options.ConsulKey, options.ConsulTree)
+	}
+
+	log.Print("Consul address and/or key not set, using local configuration file")
+
+	if len(options.FilePaths) == 0 {
+		return nil
+	}
+
+	if err := config.LoadLayered(options.FilePaths...); err != nil {
+		return err
+	}
+
+	if !options.WatchFiles {
+		return nil
+	}
+
+	// Without Consul the local files are the source of changes, so watch them instead
+	watcher, err := NewFileWatcherWithContext(ctx, options.FilePaths)
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Start(config.processFilesChanged); err != nil {
+		return fmt.Errorf("error starting watcher for changes to local configuration files: %s", err.Error())
+	}
+
+	config.fileWatcher = watcher
+	return nil
+}
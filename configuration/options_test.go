@@ -0,0 +1,117 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNewConfigurationWithOptions(t *testing.T) {
+	os.Setenv("OPTIONS_TEST_RESPONSELIMIT", "500")
+	defer os.Unsetenv("OPTIONS_TEST_RESPONSELIMIT")
+
+	target, err := NewConfigurationWithOptions(Options{
+		SectionName: "inventory-service",
+		FilePaths:   []string{"./testData/layered/base.json", "./testData/layered/configuration.d"},
+		EnvPrefix:   "OPTIONS_TEST_",
+		Defaults:    map[string]interface{}{"serverReadTimeOut": "15s"},
+	})
+	if err != nil {
+		t.Fatalf("NewConfigurationWithOptions returned error %s", err.Error())
+	}
+
+	if actual, _ := target.GetString("serviceName"); actual != "RRP Inventory Service" {
+		t.Errorf("Section value not as expected. Expected='RRP Inventory Service', Actual='%s'", actual)
+	}
+
+	if actual, _ := target.GetString("loggingLevel"); actual != "warn" {
+		t.Errorf("Layered value not as expected. Expected='warn', Actual='%s'", actual)
+	}
+
+	if actual, _ := target.GetInt("responseLimit"); actual != 500 {
+		t.Errorf("Prefixed environment value not as expected. Expected='500', Actual='%d'", actual)
+	}
+
+	if actual, _ := target.GetDuration("serverReadTimeOut"); actual != time.Second*15 {
+		t.Errorf("Default value not as expected. Expected='15s', Actual='%s'", actual)
+	}
+}
+
+func TestNewConfigurationWithOptionsSchema(t *testing.T) {
+	_, err := NewConfigurationWithOptions(Options{
+		FilePaths: []string{"./testData/layered/base.json"},
+		Schema:    `{"type": "object", "required": ["missingKey"]}`,
+	})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("Expected a *ValidationError, got %v", err)
+	}
+
+	_, err = NewConfigurationWithOptions(Options{
+		FilePaths:  []string{"./testData/layered/base.json"},
+		SchemaPath: "./testData/configSchema.json",
+	})
+	if err != nil {
+		t.Errorf("NewConfigurationWithOptions returned error for valid configuration %s", err.Error())
+	}
+}
+
+func TestNewConfigurationWithOptionsConsul(t *testing.T) {
+	appConfigKey := "config/unit-test-options-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	target, err := NewConfigurationWithOptions(Options{
+		SectionName:   "inventory-service",
+		FilePaths:     []string{"./testData/layered/base.json"},
+		ConsulAddress: consulUrl,
+		ConsulKey:     appConfigKey,
+	})
+	if err != nil {
+		t.Fatalf("NewConfigurationWithOptions returned error %s", err.Error())
+	}
+
+	verifyConfigInConsul(consulUrl, appConfigKey, t, nil)
+
+	if actual, _ := target.GetString("serviceName"); actual != "RRP Inventory Service" {
+		t.Errorf("Value pushed to Consul not as expected. Expected='RRP Inventory Service', Actual='%s'", actual)
+	}
+}
+
+func TestConfigurationClose(t *testing.T) {
+	appConfigKey := "config/unit-test-close-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	target, err := NewConfigurationWithContext(context.Background(), Options{
+		SectionName:   "inventory-service",
+		FilePaths:     []string{"./testData/layered/base.json"},
+		ConsulAddress: consulUrl,
+		ConsulKey:     appConfigKey,
+	})
+	if err != nil {
+		t.Fatalf("NewConfigurationWithContext returned error %s", err.Error())
+	}
+
+	closed := make(chan error)
+	go func() {
+		closed <- target.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Errorf("Close returned error %s", err.Error())
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for Close")
+	}
+
+	// The last configuration is still available
+	if actual, _ := target.GetString("serviceName"); actual != "RRP Inventory Service" {
+		t.Errorf("serviceName not as expected after Close. Expected='RRP Inventory Service', Actual='%s'", actual)
+	}
+}
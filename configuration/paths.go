@@ -0,0 +1,222 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/gojsonschema"
+)
+
+// Paths address a value in the document either with dots and array indexes, such as "readers[2].host", or as
+// an RFC 6901 JSON Pointer, such as "/readers/2/host". A JSON Pointer is needed for keys that contain '.', '['
+// or ']', which can't be written in the dotted form.
+const pointerSeparator = "/"
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// isPointer returns whether path is a JSON Pointer rather than a dotted path
+func isPointer(path string) bool {
+	return strings.HasPrefix(path, pointerSeparator)
+}
+
+// splitPath returns the keys and array indexes along path in either syntax
+func splitPath(path string) ([]string, error) {
+	if isPointer(path) {
+		if _, err := gojsonschema.NewJsonPointer(path); err != nil {
+			return nil, err
+		}
+
+		segments := strings.Split(path[len(pointerSeparator):], pointerSeparator)
+		for index, segment := range segments {
+			segments[index] = pointerUnescaper.Replace(segment)
+		}
+		return segments, nil
+	}
+
+	var segments []string
+	for _, node := range strings.Split(path, ".") {
+		name := node
+		var indexes []string
+		if open := strings.Index(node, "["); open >= 0 {
+			name = node[:open]
+			remaining := node[open:]
+			for remaining != "" {
+				end := strings.Index(remaining, "]")
+				if !strings.HasPrefix(remaining, "[") || end < 0 {
+					return nil, fmt.Errorf("invalid path '%s': unbalanced '[' in '%s'", path, node)
+				}
+
+				index := remaining[1:end]
+				if _, err := strconv.ParseUint(index, 10, 0); err != nil {
+					return nil, fmt.Errorf("invalid path '%s': array index '%s' is not a number", path, index)
+				}
+				indexes = append(indexes, index)
+				remaining = remaining[end+1:]
+			}
+		}
+
+		if strings.Contains(name, "]") {
+			return nil, fmt.Errorf("invalid path '%s': unbalanced ']' in '%s'", path, node)
+		}
+		if name == "" && (len(indexes) == 0 || len(segments) == 0) {
+			return nil, fmt.Errorf("invalid path '%s': empty key", path)
+		}
+
+		if name != "" {
+			segments = append(segments, name)
+		}
+		segments = append(segments, indexes...)
+	}
+
+	return segments, nil
+}
+
+// toPointer returns path, in either syntax, as a JSON Pointer
+func toPointer(path string) (string, error) {
+	if isPointer(path) || path == "" {
+		return path, nil
+	}
+
+	segments, err := splitPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	pointer := ""
+	for _, segment := range segments {
+		pointer += pointerSeparator + pointerEscaper.Replace(segment)
+	}
+
+	return pointer, nil
+}
+
+// needsPointer returns whether the key can't be written in a dotted path
+func needsPointer(key string) bool {
+	return key == "" || strings.ContainsAny(key, ".[]") || isPointer(key)
+}
+
+// joinPath appends the key to the path of its parent object. The dotted syntax is used unless the key, or a
+// key already in prefix, can only be written as a JSON Pointer.
+func joinPath(prefix string, key string) string {
+	if !isPointer(prefix) && !needsPointer(key) {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	pointer, err := toPointer(prefix)
+	if err != nil {
+		pointer = pointerSeparator + pointerEscaper.Replace(prefix)
+	}
+
+	return pointer + pointerSeparator + pointerEscaper.Replace(key)
+}
+
+// indexPath appends the array index to the path of its parent array
+func indexPath(prefix string, index int) string {
+	if isPointer(prefix) {
+		return prefix + pointerSeparator + strconv.Itoa(index)
+	}
+
+	return prefix + "[" + strconv.Itoa(index) + "]"
+}
+
+// sectionPath returns the path of the key at path within the named section
+func sectionPath(section string, path string) string {
+	if !isPointer(path) && !needsPointer(section) {
+		return section + "." + path
+	}
+
+	pointer, err := toPointer(path)
+	if err != nil {
+		return section + "." + path
+	}
+
+	return pointerSeparator + pointerEscaper.Replace(section) + pointer
+}
+
+// lookupPath walks the path, in either syntax, through the JSON document and returns the value found at the
+// end of it. A null value is treated as not found.
+func lookupPath(jsonNodes map[string]interface{}, path string) (interface{}, bool) {
+	if jsonNodes == nil || path == "" {
+		return nil, false
+	}
+
+	pointerPath, err := toPointer(path)
+	if err != nil {
+		return nil, false
+	}
+
+	pointer, err := gojsonschema.NewJsonPointer(pointerPath)
+	if err != nil {
+		return nil, false
+	}
+
+	value, _, err := pointer.Get(jsonNodes)
+	if err != nil || value == nil {
+		return nil, false
+	}
+
+	return value, true
+}
+
+// getChild returns the value of the key, or the element at the array index, in the object or array
+func getChild(parent interface{}, segment string) (interface{}, bool) {
+	switch typed := parent.(type) {
+	case map[string]interface{}:
+		child, found := typed[segment]
+		return child, found
+	case []interface{}:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(typed) {
+			return nil, false
+		}
+		return typed[index], true
+	}
+
+	return nil, false
+}
+
+// setChild stores the value at the key of the object or at the index of the array, which must already exist
+func setChild(parent interface{}, segment string, value interface{}) error {
+	switch typed := parent.(type) {
+	case map[string]interface{}:
+		typed[segment] = value
+		return nil
+	case []interface{}:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index >= len(typed) {
+			return fmt.Errorf("array index '%s' out of range [0,%d)", segment, len(typed))
+		}
+		typed[index] = value
+		return nil
+	}
+
+	return fmt.Errorf("'%s' is not in an object or array", segment)
+}
+
+// copyContainer returns a shallow copy of an object or array, which is changed in place of the original since
+// the original may be shared with a document readers are using. Any other value isn't copied.
+func copyContainer(value interface{}) (interface{}, bool) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(typed))
+		for key, child := range typed {
+			copied[key] = child
+		}
+		return copied, true
+	case []interface{}:
+		return append([]interface{}(nil), typed...), true
+	}
+
+	return value, false
+}
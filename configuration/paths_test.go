@@ -0,0 +1,175 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"reflect"
+	"testing"
+)
+
+const pathsTestJson = `{
+	"readers": [{"host": "reader-0"}, {"host": "reader-1"}, {"host": "reader-2", "ports": [8080, 8081]}],
+	"influx.v2": {"url": "http://influx:8086", "a/b": "slash", "m~n": "tilde"},
+	"inventory-service": {"readers": [{"host": "section-reader"}], "influx.v2": {"url": "http://section-influx:8086"}}
+}`
+
+func TestPathSyntax(t *testing.T) {
+	target := &Configuration{}
+	target.processConfigurationChanged([]byte(pathsTestJson))
+
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"readers[2].host", "reader-2"},
+		{"readers.2.host", "reader-2"},
+		{"/readers/2/host", "reader-2"},
+		{"/influx.v2/url", "http://influx:8086"},
+		{"/influx.v2/a~1b", "slash"},
+		{"/influx.v2/m~0n", "tilde"},
+		{"inventory-service.readers[0].host", "section-reader"},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := target.GetString(testCase.path)
+		if err != nil {
+			t.Errorf("GetString('%s') returned error %s", testCase.path, err.Error())
+			continue
+		}
+		if actual != testCase.expected {
+			t.Errorf("Value for '%s' not as expected. Expected='%s', Actual='%s'", testCase.path, testCase.expected, actual)
+		}
+	}
+
+	if actual, _ := target.GetInt("readers[2].ports[1]"); actual != 8081 {
+		t.Errorf("readers[2].ports[1] not as expected. Expected='8081', Actual='%d'", actual)
+	}
+
+	for _, path := range []string{"readers[3].host", "readers[x].host", "readers[1.host", "readers]1[.host", "influx.v2.url", "/readers/-1/host"} {
+		if _, err := target.GetString(path); !isNotFound(err) {
+			t.Errorf("Expected '%s' not to be found, got %v", path, err)
+		}
+	}
+}
+
+func TestPathSyntaxInSection(t *testing.T) {
+	target := &Configuration{sectionName: "inventory-service"}
+	target.processConfigurationChanged([]byte(pathsTestJson))
+
+	if actual, _ := target.GetString("readers[0].host"); actual != "section-reader" {
+		t.Errorf("Section readers[0].host not as expected. Expected='section-reader', Actual='%s'", actual)
+	}
+
+	if actual, _ := target.GetString("/influx.v2/url"); actual != "http://section-influx:8086" {
+		t.Errorf("Section /influx.v2/url not as expected. Expected='http://section-influx:8086', Actual='%s'", actual)
+	}
+
+	// Falls back to the global keys the same as a dotted path
+	if actual, _ := target.GetString("/readers/2/host"); actual != "reader-2" {
+		t.Errorf("Global /readers/2/host not as expected. Expected='reader-2', Actual='%s'", actual)
+	}
+}
+
+func TestPathChangeNames(t *testing.T) {
+	target := &Configuration{}
+	target.processConfigurationChanged([]byte(`{"readers": [{"host": "a"}, {"host": "b"}], "tags": ["x"], "log.level": "info"}`))
+
+	var actualChanges []ChangeDetails
+	target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+		actualChanges = changes
+	})
+
+	var subscribed interface{}
+	target.Subscribe("readers[1].host", func(oldValue interface{}, newValue interface{}) {
+		subscribed = newValue
+	})
+
+	target.processConfigurationChanged([]byte(`{"readers": [{"host": "a"}, {"host": "c"}], "tags": ["x", "y"], "log.level": "debug"}`))
+
+	expected := []ChangeDetails{
+		{Name: "/log.level", Value: "debug", OldValue: "info", Operation: Updated},
+		{Name: "readers[1].host", Value: "c", OldValue: "b", Operation: Updated},
+		{Name: "tags", Value: []interface{}{"x", "y"}, OldValue: []interface{}{"x"}, Operation: Updated},
+	}
+	if !reflect.DeepEqual(expected, actualChanges) {
+		t.Errorf("Changes not as expected.\nExpected='%v'\nActual='%v'", expected, actualChanges)
+	}
+
+	if subscribed != "c" {
+		t.Errorf("Subscription to readers[1].host not called with the new value, got %v", subscribed)
+	}
+
+	// Every name can be read back with the getters
+	for _, change := range actualChanges {
+		if _, found := target.getValue(change.Name); !found {
+			t.Errorf("Change name '%s' not found by the getters", change.Name)
+		}
+	}
+}
+
+func TestSplitPath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected []string
+	}{
+		{"port", []string{"port"}},
+		{"a.b[2].c", []string{"a", "b", "2", "c"}},
+		{"matrix[1][0]", []string{"matrix", "1", "0"}},
+		{"/a/b/2/c", []string{"a", "b", "2", "c"}},
+		{"/a.b/c~1d/e~0f", []string{"a.b", "c/d", "e~f"}},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := splitPath(testCase.path)
+		if err != nil {
+			t.Errorf("splitPath('%s') returned error %s", testCase.path, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(testCase.expected, actual) {
+			t.Errorf("Segments of '%s' not as expected. Expected='%v', Actual='%v'", testCase.path, testCase.expected, actual)
+		}
+	}
+
+	for _, path := range []string{"a..b", "a[", "a[b]", "a]", "[0].a", "a[-1]"} {
+		if _, err := splitPath(path); err == nil {
+			t.Errorf("Expected an error for '%s'", path)
+		}
+	}
+}
+
+func TestSetAndDeletePathInArray(t *testing.T) {
+	original := parseTestJson(`{"readers": [{"host": "a"}, {"host": "b"}, {"host": "c"}]}`, t)
+	document := make(map[string]interface{})
+	mergeDocuments(document, original)
+
+	if err := setPath(document, "readers[1].host", "changed"); err != nil {
+		t.Fatalf("setPath returned error %s", err.Error())
+	}
+	if err := setPath(document, "/readers/0/port", "8080"); err != nil {
+		t.Fatalf("setPath returned error %s", err.Error())
+	}
+	if err := setPath(document, "readers[3].host", "d"); err == nil {
+		t.Error("Expected an error setting an element past the end of the array")
+	}
+
+	if !deletePath(document, "readers[2]") {
+		t.Error("Expected readers[2] to be deleted")
+	}
+
+	expected := parseTestJson(`{"readers": [{"host": "a", "port": "8080"}, {"host": "changed"}]}`, t)
+	if !reflect.DeepEqual(expected, document) {
+		t.Errorf("Document not as expected.\nExpected='%v'\nActual='%v'", expected, document)
+	}
+
+	// The document copied from is left as it was
+	if actual, _ := lookupPath(original, "readers[1].host"); actual != "b" {
+		t.Errorf("Original document changed. Expected='b', Actual='%v'", actual)
+	}
+	if readers, _ := lookupPath(original, "readers"); len(readers.([]interface{})) != 3 {
+		t.Errorf("Original array changed, got %v", readers)
+	}
+}
@@ -0,0 +1,68 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"fmt"
+	"log"
+)
+
+// profilesKey is the key of the block holding the overlay for each profile:
+//
+//	"profiles": {
+//	  "prod": {"loggingLevel": "warn", "inventory-service": {"responseLimit": 1000}}
+//	}
+const profilesKey = "profiles"
+
+// Profile returns the name of the active profile, or an empty string when no profile is active
+func (config *Configuration) Profile() string {
+	return config.profile
+}
+
+// applyProfile returns a copy of the document with the active profile merged over the global keys and
+// sections, and the profiles block removed. The getters and change detection only ever see the result, so a
+// key changed in the active profile is notified like any other, and a change to another profile is not.
+func (config *Configuration) applyProfile(parsedJson map[string]interface{}) (map[string]interface{}, error) {
+	profiles, hasProfiles := parsedJson[profilesKey]
+	if !hasProfiles {
+		return parsedJson, nil
+	}
+
+	profilesObject, ok := profiles.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("'%s' must be an object of profiles: Value='%v'", profilesKey, config.redact(profiles))
+	}
+
+	applied := make(map[string]interface{}, len(parsedJson))
+	for key, value := range parsedJson {
+		if key != profilesKey {
+			applied[key] = value
+		}
+	}
+
+	if config.profile == "" {
+		return applied, nil
+	}
+
+	profile, found := profilesObject[config.profile]
+	if !found {
+		log.Printf("Profile '%s' not found in configuration, using the configuration without a profile", config.profile)
+		return applied, nil
+	}
+
+	profileObject, ok := profile.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' must be an object: Value='%v'", config.profile, config.redact(profile))
+	}
+
+	// Merge into copies of the objects the profile changes so the document as written is left alone
+	merged := make(map[string]interface{}, len(applied))
+	mergeDocuments(merged, applied)
+	mergeDocuments(merged, profileObject)
+
+	return merged, nil
+}
@@ -0,0 +1,112 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"reflect"
+	"testing"
+)
+
+const profilesTestJson = `{
+	"loggingLevel": "info",
+	"influx": {"url": "http://influxdb:8086", "database": "inventory"},
+	"inventory-service": {"responseLimit": 100, "serviceName": "Inventory"},
+	"profiles": {
+		"prod": {
+			"loggingLevel": "warn",
+			"influx": {"url": "http://influx-prod:8086"},
+			"inventory-service": {"responseLimit": 1000}
+		},
+		"dev": {"loggingLevel": "debug"}
+	}
+}`
+
+func TestProfiles(t *testing.T) {
+	testCases := []struct {
+		profile       string
+		loggingLevel  string
+		influxUrl     string
+		responseLimit int
+	}{
+		{"", "info", "http://influxdb:8086", 100},
+		{"prod", "warn", "http://influx-prod:8086", 1000},
+		{"dev", "debug", "http://influxdb:8086", 100},
+		{"bogus", "info", "http://influxdb:8086", 100},
+	}
+
+	for _, testCase := range testCases {
+		target := &Configuration{sectionName: "inventory-service", profile: testCase.profile}
+		target.processConfigurationChanged([]byte(profilesTestJson))
+
+		if actual, _ := target.GetString("loggingLevel"); actual != testCase.loggingLevel {
+			t.Errorf("loggingLevel not as expected for profile '%s'. Expected='%s', Actual='%s'", testCase.profile, testCase.loggingLevel, actual)
+		}
+
+		if actual, _ := target.GetString("influx.url"); actual != testCase.influxUrl {
+			t.Errorf("influx.url not as expected for profile '%s'. Expected='%s', Actual='%s'", testCase.profile, testCase.influxUrl, actual)
+		}
+
+		if actual, _ := target.GetInt("responseLimit"); actual != testCase.responseLimit {
+			t.Errorf("responseLimit not as expected for profile '%s'. Expected='%d', Actual='%d'", testCase.profile, testCase.responseLimit, actual)
+		}
+
+		// Keys the profile doesn't set are kept
+		if actual, _ := target.GetString("influx.database"); actual != "inventory" {
+			t.Errorf("influx.database not as expected for profile '%s'. Expected='inventory', Actual='%s'", testCase.profile, actual)
+		}
+
+		if _, err := target.GetNestedJSON("profiles"); !isNotFound(err) {
+			t.Errorf("Expected profiles to be hidden from the getters, got %v", err)
+		}
+	}
+
+	target := &Configuration{profile: "prod"}
+	target.processConfigurationChanged([]byte(profilesTestJson))
+	if raw := target.current.Load().(*configSnapshot).document; raw["loggingLevel"] != "info" {
+		t.Errorf("Expected the document as written to be left alone, got loggingLevel='%v'", raw["loggingLevel"])
+	}
+}
+
+func TestProfileChanges(t *testing.T) {
+	target := &Configuration{sectionName: "inventory-service", profile: "prod"}
+	target.processConfigurationChanged([]byte(`{
+		"loggingLevel": "info",
+		"inventory-service": {"responseLimit": 100},
+		"profiles": {"prod": {"inventory-service": {"responseLimit": 1000}}, "dev": {"loggingLevel": "debug"}}
+	}`))
+
+	var actualChanges []ChangeDetails
+	target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+		actualChanges = changes
+	})
+
+	// A change to another profile isn't seen
+	target.processConfigurationChanged([]byte(`{
+		"loggingLevel": "info",
+		"inventory-service": {"responseLimit": 100},
+		"profiles": {"prod": {"inventory-service": {"responseLimit": 1000}}, "dev": {"loggingLevel": "trace"}}
+	}`))
+	if actualChanges != nil {
+		t.Errorf("Expected no changes for another profile, got %v", actualChanges)
+	}
+
+	// A change hidden by the active profile isn't seen either, but one in the profile is
+	target.processConfigurationChanged([]byte(`{
+		"loggingLevel": "info",
+		"inventory-service": {"responseLimit": 200},
+		"profiles": {"prod": {"loggingLevel": "warn", "inventory-service": {"responseLimit": 1000}}, "dev": {"loggingLevel": "trace"}}
+	}`))
+
+	expected := []ChangeDetails{{Name: "loggingLevel", Value: "warn", OldValue: "info", Operation: Updated}}
+	if !reflect.DeepEqual(expected, actualChanges) {
+		t.Errorf("Changes not as expected.\nExpected='%v'\nActual='%v'", expected, actualChanges)
+	}
+
+	if _, err := (&Configuration{profile: "prod"}).applyProfile(parseTestJson(`{"profiles": ["prod"]}`, t)); err == nil {
+		t.Error("Expected an error for profiles that are not an object")
+	}
+}
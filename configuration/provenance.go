@@ -0,0 +1,102 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import "os"
+
+// Source identifies where the value of a key came from
+type Source uint
+
+const (
+	UnknownSource Source = iota
+	SectionSource
+	GlobalSource
+	EnvironmentSource
+	DefaultSource
+	FlagSource
+)
+
+func (source Source) String() string {
+	switch source {
+	case SectionSource:
+		return "section"
+	case GlobalSource:
+		return "global"
+	case EnvironmentSource:
+		return "env"
+	case DefaultSource:
+		return "default"
+	case FlagSource:
+		return "flag"
+	}
+
+	return "unknown"
+}
+
+// MarshalText writes the source by name in JSON
+func (source Source) MarshalText() ([]byte, error) {
+	return []byte(source.String()), nil
+}
+
+// Explanation describes the value the getters return for a key and where it came from. Key is the full path
+// in the document or defaults, or the name of the environment variable or flag, the value was read from.
+type Explanation struct {
+	Path   string      `json:"path"`
+	Value  interface{} `json:"value"`
+	Source Source      `json:"source"`
+	Key    string      `json:"key"`
+}
+
+// Explain returns the value of the key at path and where it came from, using the same precedence as the
+// getters. Secret references are returned as written rather than resolved.
+func (config *Configuration) Explain(path string) (Explanation, error) {
+	value, source, key, found := config.locate(path)
+	if !found {
+		return Explanation{}, &notFoundError{path: path}
+	}
+
+	return Explanation{Path: path, Value: value, Source: source, Key: key}, nil
+}
+
+// locate finds the value of the key at path in the flags set on the command line, the environment variables
+// named after it, the section, the global keys, the environment variable named as the path and then the
+// defaults, and returns where it was found
+func (config *Configuration) locate(path string) (value interface{}, source Source, key string, found bool) {
+	return config.locateIn(config.parsedJson(), config.sectionName, path)
+}
+
+// locateIn is locate within the document and section given rather than the current ones
+func (config *Configuration) locateIn(parsedJson map[string]interface{}, section string, path string) (value interface{}, source Source, key string, found bool) {
+	if flagValue, name, ok := config.lookupFlag(path); ok {
+		return flagValue, FlagSource, name, true
+	}
+
+	if envValue, name, ok := config.lookupEnvOverride(section, path); ok {
+		return envValue, EnvironmentSource, name, true
+	}
+
+	if section != "" {
+		key = sectionPath(section, path)
+		if value, found = lookupPath(parsedJson, key); found {
+			return value, SectionSource, key, true
+		}
+	}
+
+	if value, found = lookupPath(parsedJson, path); found {
+		return value, GlobalSource, path, true
+	}
+
+	if envValue, ok := os.LookupEnv(path); ok {
+		return envValue, EnvironmentSource, path, true
+	}
+
+	if value, found = config.getDefault(path); found {
+		return value, DefaultSource, path, true
+	}
+
+	return nil, UnknownSource, "", false
+}
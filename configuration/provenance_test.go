@@ -0,0 +1,46 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	os.Setenv("responseLimit", "500")
+	defer os.Unsetenv("responseLimit")
+
+	target := &Configuration{sectionName: "inventory-service", envPrefix: "EXPLAIN_TEST_"}
+	target.processConfigurationChanged([]byte(`{"port": "8080", "loggingLevel": "info", "inventory-service": {"port": "8081"}}`))
+	if err := target.SetDefaults(map[string]interface{}{"serverReadTimeOut": "15s", "loggingLevel": "error"}); err != nil {
+		t.Fatalf("SetDefaults returned error %s", err.Error())
+	}
+
+	expected := []Explanation{
+		{Path: "port", Value: "8081", Source: SectionSource, Key: "inventory-service.port"},
+		{Path: "loggingLevel", Value: "info", Source: GlobalSource, Key: "loggingLevel"},
+		{Path: "responseLimit", Value: "500", Source: EnvironmentSource, Key: "responseLimit"},
+		{Path: "serverReadTimeOut", Value: "15s", Source: DefaultSource, Key: "serverReadTimeOut"},
+	}
+
+	for _, expectedExplanation := range expected {
+		actual, err := target.Explain(expectedExplanation.Path)
+		if err != nil {
+			t.Errorf("Explain returned error %s", err.Error())
+			continue
+		}
+
+		if actual != expectedExplanation {
+			t.Errorf("Explanation not as expected. Expected='%+v', Actual='%+v'", expectedExplanation, actual)
+		}
+	}
+
+	if _, err := target.Explain("bogus"); !isNotFound(err) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
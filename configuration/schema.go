@@ -0,0 +1,130 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/gojsonschema"
+)
+
+// ValidationError lists the reasons a configuration document failed schema validation
+type ValidationError struct {
+	Errors []string
+}
+
+func (validationError *ValidationError) Error() string {
+	return fmt.Sprintf("configuration failed schema validation: %s", strings.Join(validationError.Errors, "; "))
+}
+
+// SetSchema sets the JSON schema every configuration document must satisfy. The current document is validated
+// immediately and an invalid document is reported as a *ValidationError, in which case the schema is not set.
+// An update being applied meanwhile is validated against the new schema, and SetSchema returns an error when
+// called from a change handler, whose update must finish first.
+func (config *Configuration) SetSchema(schema string) error {
+	return config.setSchema(gojsonschema.NewStringLoader(schema))
+}
+
+// SetSchemaFile is the same as SetSchema with the schema loaded from a file, which may $ref files relative to it
+func (config *Configuration) SetSchemaFile(path string) error {
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("unable to resolve schema path %s: %s", path, err.Error())
+	}
+
+	return config.setSchema(gojsonschema.NewReferenceLoader("file://" + filepath.ToSlash(absolutePath)))
+}
+
+// SetValidationErrorCallback sets the callback that receives the error when a configuration update is
+// rejected, such as the *ValidationError for failing schema validation or the *ChangeRejectedError when a
+// change handler returned an error
+func (config *Configuration) SetValidationErrorCallback(callback func(error)) {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	config.validationErrorCallback = callback
+}
+
+func (config *Configuration) setSchema(loader gojsonschema.JSONLoader) error {
+	schema, err := gojsonschema.NewSchema(loader)
+	if err != nil {
+		return fmt.Errorf("unable to load configuration schema: %s", err.Error())
+	}
+
+	// The update being handled holds updateMutex until the handlers return
+	config.mutex.RLock()
+	handlingChanges := config.handlingChanges
+	config.mutex.RUnlock()
+	if handlingChanges {
+		return fmt.Errorf("unable to set configuration schema while change handlers are running")
+	}
+
+	// Held until the schema is set so no update validated without it is applied after the check
+	config.updateMutex.Lock()
+	defer config.updateMutex.Unlock()
+
+	if parsedJson := config.parsedJson(); parsedJson != nil {
+		if err := validateAgainstSchema(schema, parsedJson); err != nil {
+			return err
+		}
+	}
+
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	config.schema = schema
+	return nil
+}
+
+// validate checks the document against the schema, if one has been set
+func (config *Configuration) validate(parsedJson map[string]interface{}) error {
+	config.mutex.RLock()
+	schema := config.schema
+	config.mutex.RUnlock()
+
+	if schema == nil {
+		return nil
+	}
+
+	return validateAgainstSchema(schema, parsedJson)
+}
+
+func validateAgainstSchema(schema *gojsonschema.Schema, parsedJson map[string]interface{}) error {
+	result, err := schema.Validate(gojsonschema.NewGoLoader(parsedJson))
+	if err != nil {
+		return fmt.Errorf("unable to validate configuration against schema: %s", err.Error())
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	validationError := &ValidationError{}
+	for _, resultError := range result.Errors() {
+		validationError.Errors = append(validationError.Errors, resultError.String())
+	}
+
+	return validationError
+}
+
+// rejectUpdate logs why an update was not applied and passes the error on to the validation error callback
+func (config *Configuration) rejectUpdate(err error) {
+	log.Printf("rejected configuration update, keeping last known good configuration: %s", err.Error())
+
+	config.queueNotification(func() {
+		config.mutex.RLock()
+		validationErrorCallback := config.validationErrorCallback
+		config.mutex.RUnlock()
+
+		if validationErrorCallback != nil {
+			validationErrorCallback(err)
+		}
+	})
+}
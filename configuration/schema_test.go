@@ -0,0 +1,170 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSchemaFile = "./testData/configSchema.json"
+
+func TestSetSchemaValidDocument(t *testing.T) {
+	target := &Configuration{}
+	if err := target.Load("./testData/sectionedConfig.json"); err != nil {
+		t.Fatalf("Config file not loaded: %s", err.Error())
+	}
+
+	if err := target.SetSchemaFile(testSchemaFile); err != nil {
+		t.Fatalf("SetSchemaFile returned error %s", err.Error())
+	}
+}
+
+func TestSetSchemaInvalidDocument(t *testing.T) {
+	target := &Configuration{}
+	if err := target.Load("./testData/simpleConfig.json"); err != nil {
+		t.Fatalf("Config file not loaded: %s", err.Error())
+	}
+
+	err := target.SetSchemaFile(testSchemaFile)
+	if _, ok := err.(*ValidationError); !ok {
+		t.Fatalf("expected *ValidationError for missing port and got %v", err)
+	}
+
+	if target.schema != nil {
+		t.Error("schema should not be set when the current document is invalid")
+	}
+}
+
+func TestSetSchemaDuringUpdate(t *testing.T) {
+	target := &Configuration{}
+	if err := target.Load("./testData/sectionedConfig.json"); err != nil {
+		t.Fatalf("Config file not loaded: %s", err.Error())
+	}
+
+	// An update validated before the schema was set is still being applied
+	target.updateMutex.Lock()
+
+	done := make(chan error)
+	go func() {
+		done <- target.SetSchemaFile(testSchemaFile)
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("SetSchemaFile returned while an update was being applied: %v", err)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	invalid := map[string]interface{}{"loggingLevel": "info"}
+	target.setParsedJson(invalid, invalid, 0)
+	target.updateMutex.Unlock()
+
+	if _, ok := (<-done).(*ValidationError); !ok {
+		t.Error("expected *ValidationError for the document applied before the schema was set")
+	}
+}
+
+func TestSetSchemaBadSchema(t *testing.T) {
+	target := &Configuration{}
+
+	if err := target.SetSchema(`{"type": 12}`); err == nil {
+		t.Error("expected error for invalid schema")
+	}
+}
+
+func TestLoadRejectedBySchema(t *testing.T) {
+	target := &Configuration{}
+	if err := target.Load("./testData/sectionedConfig.json"); err != nil {
+		t.Fatalf("Config file not loaded: %s", err.Error())
+	}
+
+	if err := target.SetSchemaFile(testSchemaFile); err != nil {
+		t.Fatalf("SetSchemaFile returned error %s", err.Error())
+	}
+
+	if err := target.Load("./testData/simpleConfig.json"); err == nil {
+		t.Fatal("expected schema validation error loading simpleConfig.json")
+	}
+
+	// The previously loaded document is kept
+	actual, err := target.GetString("port")
+	if err != nil || actual != "8080" {
+		t.Errorf("expected last known good port 8080 and got '%s' %v", actual, err)
+	}
+}
+
+func TestConsulUpdateRejectedBySchema(t *testing.T) {
+	target := &Configuration{}
+	target.processConfigurationChanged([]byte(`{"port": "8080", "responseLimit": 100}`))
+
+	if err := target.SetSchemaFile(testSchemaFile); err != nil {
+		t.Fatalf("SetSchemaFile returned error %s", err.Error())
+	}
+
+	var validationErr error
+	target.SetValidationErrorCallback(func(err error) {
+		validationErr = err
+	})
+
+	changeCallbackCalled := false
+	target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+		changeCallbackCalled = true
+	})
+
+	target.processConfigurationChanged([]byte(`{"port": "not a number", "responseLimit": 0}`))
+
+	validationError, ok := validationErr.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected *ValidationError from callback and got %v", validationErr)
+	}
+
+	if len(validationError.Errors) != 2 {
+		t.Errorf("expected 2 validation errors and got %d: %s", len(validationError.Errors), validationError.Error())
+	}
+
+	if changeCallbackCalled {
+		t.Error("unexpected config changed callback for rejected update")
+	}
+
+	actual, err := target.GetString("port")
+	if err != nil || actual != "8080" {
+		t.Errorf("expected last known good port 8080 and got '%s' %v", actual, err)
+	}
+
+	target.processConfigurationChanged([]byte(`{"port": "9090", "responseLimit": 10}`))
+
+	if !changeCallbackCalled {
+		t.Error("expected config changed callback for valid update")
+	}
+}
+
+func TestSchemaEnforcedOnInitialLoad(t *testing.T) {
+	restoreDefaultConfiguration()
+
+	schemaFile, err := ioutil.TempFile("", "schema")
+	if err != nil {
+		t.Fatalf("unable to create schema file: %s", err.Error())
+	}
+	defer os.Remove(schemaFile.Name())
+
+	if _, err := schemaFile.WriteString(`{"type": "object", "required": ["bogusRequired"]}`); err != nil {
+		t.Fatalf("unable to write schema file: %s", err.Error())
+	}
+	schemaFile.Close()
+
+	os.Setenv("configSchemaPath", schemaFile.Name())
+	defer os.Unsetenv("configSchemaPath")
+
+	_, err = NewConfiguration()
+	if err == nil || !strings.Contains(err.Error(), "bogusRequired") {
+		t.Errorf("expected schema validation error for missing bogusRequired and got %v", err)
+	}
+}
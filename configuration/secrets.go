@@ -0,0 +1,195 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/helper"
+)
+
+const (
+	secretScheme = "secret://"
+	redactedText = "******"
+)
+
+// fileReferencePattern matches ${file:/path/to/secret} anywhere in a string value
+var fileReferencePattern = regexp.MustCompile(`\$\{file:([^}]+)\}`)
+
+// secretCache holds the secrets resolved by the getters. Each secret is read again when its file changes.
+// The zero value is ready to use.
+type secretCache struct {
+	mutex   sync.Mutex
+	secrets map[string]cachedSecret
+}
+
+type cachedSecret struct {
+	value string
+	state fileState
+}
+
+// resolveSecrets replaces the secret references in a value with the secrets they refer to. A string value of
+// "secret://name" is replaced by the secret read with helper.GetSecret, and each "${file:/path}" within a
+// string is replaced by the content of the file. Trailing line breaks are removed from the secrets. Maps and
+// slices are resolved recursively and copied when they hold references, so the document is never modified.
+func (config *Configuration) resolveSecrets(path string, value interface{}) (interface{}, error) {
+	resolved, err := config.secrets.resolve(value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve secret for '%s': %s", path, err.Error())
+	}
+
+	return resolved, nil
+}
+
+func (cache *secretCache) resolve(value interface{}) (interface{}, error) {
+	resolved, _, err := cache.resolveValue(value)
+	return resolved, err
+}
+
+// resolveValue returns the resolved value and whether it differs from the original
+func (cache *secretCache) resolveValue(value interface{}) (interface{}, bool, error) {
+	switch typedValue := value.(type) {
+	case string:
+		resolved, err := cache.resolveString(typedValue)
+		if err != nil {
+			return nil, false, err
+		}
+		return resolved, resolved != typedValue, nil
+
+	case map[string]interface{}:
+		var resolvedMap map[string]interface{}
+		for key, item := range typedValue {
+			resolvedItem, changed, err := cache.resolveValue(item)
+			if err != nil {
+				return nil, false, err
+			}
+			if !changed {
+				continue
+			}
+
+			if resolvedMap == nil {
+				resolvedMap = make(map[string]interface{}, len(typedValue))
+				for copyKey, copyItem := range typedValue {
+					resolvedMap[copyKey] = copyItem
+				}
+			}
+			resolvedMap[key] = resolvedItem
+		}
+
+		if resolvedMap == nil {
+			return typedValue, false, nil
+		}
+		return resolvedMap, true, nil
+
+	case []interface{}:
+		var resolvedSlice []interface{}
+		for index, item := range typedValue {
+			resolvedItem, changed, err := cache.resolveValue(item)
+			if err != nil {
+				return nil, false, err
+			}
+			if !changed {
+				continue
+			}
+
+			if resolvedSlice == nil {
+				resolvedSlice = append([]interface{}(nil), typedValue...)
+			}
+			resolvedSlice[index] = resolvedItem
+		}
+
+		if resolvedSlice == nil {
+			return typedValue, false, nil
+		}
+		return resolvedSlice, true, nil
+	}
+
+	return value, false, nil
+}
+
+func (cache *secretCache) resolveString(value string) (string, error) {
+	if strings.HasPrefix(value, secretScheme) {
+		return cache.read(strings.TrimPrefix(value, secretScheme))
+	}
+
+	if !strings.Contains(value, "${file:") {
+		return value, nil
+	}
+
+	var resolveErr error
+	resolved := fileReferencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		secret, err := cache.read(fileReferencePattern.FindStringSubmatch(reference)[1])
+		if err != nil && resolveErr == nil {
+			resolveErr = err
+		}
+		return secret
+	})
+
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+
+	return resolved, nil
+}
+
+// read returns the secret, reading it with helper.GetSecret when it is not cached or the file has changed
+func (cache *secretCache) read(name string) (string, error) {
+	// The same rule as helper.GetSecret for which names are paths
+	filePath := name
+	if !strings.Contains(name, "/") {
+		filePath = filepath.Join(secretsDirectory, name)
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", err
+	}
+	state := fileState{path: filePath, size: info.Size(), modTime: info.ModTime()}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cached, ok := cache.secrets[filePath]; ok && cached.state == state {
+		return cached.value, nil
+	}
+
+	secret, err := helper.GetSecret(name)
+	if err != nil {
+		return "", err
+	}
+	secret = strings.TrimRight(secret, "\r\n")
+
+	if cache.secrets == nil {
+		cache.secrets = make(map[string]cachedSecret)
+	}
+	cache.secrets[filePath] = cachedSecret{value: secret, state: state}
+
+	return secret, nil
+}
+
+// redact replaces every resolved secret in the text of a value so it can be logged or returned in an error
+func (config *Configuration) redact(value interface{}) string {
+	return config.secrets.redact(fmt.Sprint(value))
+}
+
+func (cache *secretCache) redact(text string) string {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	for _, cached := range cache.secrets {
+		if cached.value != "" {
+			text = strings.Replace(text, cached.value, redactedText, -1)
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,113 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadSecretConfig(t *testing.T) (*Configuration, string) {
+	directory, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestFile(t, filepath.Join(directory, "influxPassword"), "hunter2\n")
+	writeTestFile(t, filepath.Join(directory, "dbPassword"), "s3cr3t")
+
+	document := fmt.Sprintf(`{
+		"influx": {"user": "admin", "password": "secret://%[1]s/influxPassword"},
+		"dbUrl": "postgres://admin:${file:%[1]s/dbPassword}@db:5432/inventory",
+		"badSecret": "secret://%[1]s/missing"
+	}`, directory)
+
+	target := &Configuration{}
+	target.processConfigurationChanged([]byte(document))
+
+	return target, directory
+}
+
+func TestSecretReferences(t *testing.T) {
+	target, directory := loadSecretConfig(t)
+	defer os.RemoveAll(directory)
+
+	if actual, err := target.GetString("influx.password"); err != nil || actual != "hunter2" {
+		t.Errorf("Secret not resolved. Expected='hunter2', Actual='%s', Error='%v'", actual, err)
+	}
+
+	expectedUrl := "postgres://admin:s3cr3t@db:5432/inventory"
+	if actual, err := target.GetString("dbUrl"); err != nil || actual != expectedUrl {
+		t.Errorf("File reference not resolved. Expected='%s', Actual='%s', Error='%v'", expectedUrl, actual, err)
+	}
+
+	expectedInflux := map[string]interface{}{"user": "admin", "password": "hunter2"}
+	if actual, err := target.GetNestedJSON("influx"); err != nil || !reflect.DeepEqual(expectedInflux, actual) {
+		t.Errorf("Nested secret not resolved. Expected='%v', Actual='%v', Error='%v'", expectedInflux, actual, err)
+	}
+
+	var settings struct {
+		Influx struct {
+			Password string `config:"password"`
+		} `config:"influx"`
+	}
+	if err := target.Unmarshal("", &settings); err != nil || settings.Influx.Password != "hunter2" {
+		t.Errorf("Secret not resolved by Unmarshal. Actual='%s', Error='%v'", settings.Influx.Password, err)
+	}
+
+	// The document itself keeps the references
+	influx := target.GetParsedJson()["influx"].(map[string]interface{})
+	if !strings.HasPrefix(influx["password"].(string), secretScheme) {
+		t.Errorf("Expected the document to hold the secret reference, got '%v'", influx["password"])
+	}
+
+	if _, err := target.GetString("badSecret"); err == nil || !strings.Contains(err.Error(), "badSecret") {
+		t.Errorf("Expected error for missing secret file, got %v", err)
+	}
+}
+
+func TestSecretReresolvedOnChange(t *testing.T) {
+	target, directory := loadSecretConfig(t)
+	defer os.RemoveAll(directory)
+
+	if actual, _ := target.GetString("influx.password"); actual != "hunter2" {
+		t.Fatalf("Secret not resolved. Expected='hunter2', Actual='%s'", actual)
+	}
+
+	writeTestFile(t, filepath.Join(directory, "influxPassword"), "correct horse battery staple\n")
+
+	if actual, _ := target.GetString("influx.password"); actual != "correct horse battery staple" {
+		t.Errorf("Secret not re-resolved after change. Expected='correct horse battery staple', Actual='%s'", actual)
+	}
+}
+
+func TestSecretRedactedFromErrors(t *testing.T) {
+	target, directory := loadSecretConfig(t)
+	defer os.RemoveAll(directory)
+
+	_, err := target.GetInt("influx.password")
+	if err == nil {
+		t.Fatal("Expected an error, but didn't get it")
+	}
+
+	if strings.Contains(err.Error(), "hunter2") || !strings.Contains(err.Error(), redactedText) {
+		t.Errorf("Expected secret to be redacted from error: %s", err.Error())
+	}
+
+	var settings struct {
+		DbUrl int `config:"dbUrl"`
+	}
+	err = target.Unmarshal("", &settings)
+	if err == nil || strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("Expected secret to be redacted from Unmarshal error: %v", err)
+	}
+}
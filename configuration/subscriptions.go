@@ -0,0 +1,191 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"path"
+	"strings"
+)
+
+const wildcardSegment = "*"
+
+type subscription struct {
+	id       uint64
+	pattern  []string
+	callback func(oldValue interface{}, newValue interface{})
+}
+
+// Subscribe registers a callback that is called with the old and new value of every changed key matching pattern.
+// The pattern is a dotted path relative to the section, such as "influx.url", so it matches the key whether it
+// changed in the section or in the global keys. A change the getters don't return, such as to a global key the
+// section overrides, isn't passed to the callback. Each segment may use path.Match wildcards, and a final "*"
+// segment matches every key beneath the prefix, so "influx.*" matches both "influx.url" and "influx.pool.size".
+// Array elements and keys containing dots are matched with the same paths the getters take, such as
+// "readers[2].host" or "/influx.v2/url".
+// The returned function removes the subscription.
+func (config *Configuration) Subscribe(pattern string, callback func(oldValue interface{}, newValue interface{})) func() {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	config.nextSubscriptionId++
+	id := config.nextSubscriptionId
+
+	config.subscriptions = append(config.subscriptions, &subscription{
+		id:       id,
+		pattern:  splitPattern(pattern),
+		callback: callback,
+	})
+
+	return func() {
+		config.unsubscribe(id)
+	}
+}
+
+func (config *Configuration) unsubscribe(id uint64) {
+	config.mutex.Lock()
+	defer config.mutex.Unlock()
+
+	for index, existing := range config.subscriptions {
+		if existing.id == id {
+			// Copy rather than modify in place since notifyChanges may be iterating the current slice
+			subscriptions := make([]*subscription, 0, len(config.subscriptions)-1)
+			subscriptions = append(subscriptions, config.subscriptions[:index]...)
+			config.subscriptions = append(subscriptions, config.subscriptions[index+1:]...)
+			return
+		}
+	}
+}
+
+// queueNotification adds a callback to be delivered by deliverNotifications. Callers must hold updateMutex.
+func (config *Configuration) queueNotification(notification func()) {
+	config.notifyMutex.Lock()
+	defer config.notifyMutex.Unlock()
+
+	config.notifications = append(config.notifications, notification)
+}
+
+// deliverNotifications calls the queued callbacks in the order they were queued. Only one goroutine delivers
+// at a time, so a callback that changes the configuration returns before the notifications of its change are
+// delivered, and they are delivered by the call already delivering once the callback returns.
+func (config *Configuration) deliverNotifications() {
+	config.notifyMutex.Lock()
+	if config.notifying {
+		config.notifyMutex.Unlock()
+		return
+	}
+	config.notifying = true
+
+	for len(config.notifications) > 0 {
+		notification := config.notifications[0]
+		config.notifications = config.notifications[1:]
+
+		config.notifyMutex.Unlock()
+		notification()
+		config.notifyMutex.Lock()
+	}
+
+	config.notifying = false
+	config.notifyMutex.Unlock()
+}
+
+// notifyChanges passes the changes to the change callback and to every subscription matching a changed key.
+// parsedJson is the document the changes were made to.
+func (config *Configuration) notifyChanges(changedList []ChangeDetails, parsedJson map[string]interface{}) {
+	config.mutex.RLock()
+	configChangeCallback := config.configChangeCallback
+	subscriptions := config.subscriptions
+	config.mutex.RUnlock()
+
+	if configChangeCallback != nil {
+		configChangeCallback(changedList)
+	}
+
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	for _, change := range changedList {
+		segments, global, ok := config.subscriptionSegments(change.Name)
+		if !ok || config.shadowed(parsedJson, segments, global) {
+			continue
+		}
+
+		for _, subscriber := range subscriptions {
+			if matchPattern(subscriber.pattern, segments) {
+				subscriber.callback(change.OldValue, change.Value)
+			}
+		}
+	}
+}
+
+// subscriptionSegments returns the segments of the changed key relative to the section, and whether it is a
+// global key
+func (config *Configuration) subscriptionSegments(name string) ([]string, bool, bool) {
+	segments, err := splitPath(name)
+	if err != nil {
+		return nil, false, false
+	}
+
+	if config.sectionName != "" && len(segments) > 1 && segments[0] == config.sectionName {
+		return segments[1:], false, true
+	}
+
+	return segments, true, true
+}
+
+// shadowed returns whether the getters read the key from ahead of where it changed, using the same precedence
+// as locate: a flag or environment variable bound to the key, or the section when a global key changed
+func (config *Configuration) shadowed(parsedJson map[string]interface{}, segments []string, global bool) bool {
+	path := ""
+	for _, segment := range segments {
+		path = joinPath(path, segment)
+	}
+
+	if _, _, found := config.lookupFlag(path); found {
+		return true
+	}
+
+	if _, _, found := config.lookupEnvOverride(config.sectionName, path); found {
+		return true
+	}
+
+	if global && config.sectionName != "" {
+		_, found := lookupPath(parsedJson, sectionPath(config.sectionName, path))
+		return found
+	}
+
+	return false
+}
+
+// splitPattern splits the pattern the same as a path, except a pattern that isn't a valid path, such as one
+// using a path.Match character class, is split on dots alone
+func splitPattern(pattern string) []string {
+	if segments, err := splitPath(pattern); err == nil {
+		return segments
+	}
+
+	return strings.Split(pattern, ".")
+}
+
+func matchPattern(pattern []string, segments []string) bool {
+	for index, patternSegment := range pattern {
+		if index >= len(segments) {
+			return false
+		}
+
+		if patternSegment == wildcardSegment && index == len(pattern)-1 {
+			return true
+		}
+
+		matched, err := path.Match(patternSegment, segments[index])
+		if err != nil || !matched {
+			return false
+		}
+	}
+
+	return len(pattern) == len(segments)
+}
@@ -0,0 +1,128 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"reflect"
+	"testing"
+)
+
+type subscriptionCall struct {
+	oldValue interface{}
+	newValue interface{}
+}
+
+func TestSubscribeExactKey(t *testing.T) {
+	target := &Configuration{sectionName: "unit-test"}
+	target.processConfigurationChanged([]byte(`{"port": "8080", "loggingLevel": "info", "unit-test": {"dbPoolSize": 5}}`))
+
+	var portCalls, poolCalls []subscriptionCall
+	target.Subscribe("port", func(oldValue interface{}, newValue interface{}) {
+		portCalls = append(portCalls, subscriptionCall{oldValue, newValue})
+	})
+	target.Subscribe("dbPoolSize", func(oldValue interface{}, newValue interface{}) {
+		poolCalls = append(poolCalls, subscriptionCall{oldValue, newValue})
+	})
+
+	target.processConfigurationChanged([]byte(`{"port": "9090", "loggingLevel": "debug", "unit-test": {"dbPoolSize": 10}}`))
+
+	expectedPort := []subscriptionCall{{"8080", "9090"}}
+	if !reflect.DeepEqual(expectedPort, portCalls) {
+		t.Errorf("port subscription calls not as expected. Expected='%v', Actual='%v'", expectedPort, portCalls)
+	}
+
+	expectedPool := []subscriptionCall{{float64(5), float64(10)}}
+	if !reflect.DeepEqual(expectedPool, poolCalls) {
+		t.Errorf("dbPoolSize subscription calls not as expected. Expected='%v', Actual='%v'", expectedPool, poolCalls)
+	}
+}
+
+func TestSubscribeWildcard(t *testing.T) {
+	target := &Configuration{sectionName: "unit-test"}
+	target.processConfigurationChanged([]byte(`{"port": "8080", "unit-test": {"influx": {"url": "a", "pool": {"size": 1}}}}`))
+
+	var calls []subscriptionCall
+	target.Subscribe("influx.*", func(oldValue interface{}, newValue interface{}) {
+		calls = append(calls, subscriptionCall{oldValue, newValue})
+	})
+
+	target.processConfigurationChanged([]byte(`{"port": "9090", "unit-test": {"influx": {"url": "b", "pool": {"size": 2}}}}`))
+
+	expected := []subscriptionCall{{float64(1), float64(2)}, {"a", "b"}}
+	if !reflect.DeepEqual(expected, calls) {
+		t.Errorf("influx.* subscription calls not as expected. Expected='%v', Actual='%v'", expected, calls)
+	}
+}
+
+func TestSubscribeShadowedKey(t *testing.T) {
+	target := &Configuration{sectionName: "unit-test"}
+	target.processConfigurationChanged([]byte(`{"port": "1", "unit-test": {"port": "9"}}`))
+
+	var calls []subscriptionCall
+	target.Subscribe("port", func(oldValue interface{}, newValue interface{}) {
+		calls = append(calls, subscriptionCall{oldValue, newValue})
+	})
+
+	// The section's port is the one the getters return, so the global change isn't passed on
+	target.processConfigurationChanged([]byte(`{"port": "2", "unit-test": {"port": "9"}}`))
+
+	if len(calls) != 0 {
+		t.Errorf("Expected no calls for the shadowed global port, got %v", calls)
+	}
+	if actual, _ := target.GetString("port"); actual != "9" {
+		t.Errorf("port not as expected. Expected='9', Actual='%s'", actual)
+	}
+
+	target.processConfigurationChanged([]byte(`{"port": "2", "unit-test": {"port": "10"}}`))
+
+	expected := []subscriptionCall{{"9", "10"}}
+	if !reflect.DeepEqual(expected, calls) {
+		t.Errorf("port subscription calls not as expected. Expected='%v', Actual='%v'", expected, calls)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	target := &Configuration{}
+	target.processConfigurationChanged([]byte(`{"port": "8080"}`))
+
+	callCount := 0
+	unsubscribe := target.Subscribe("port", func(oldValue interface{}, newValue interface{}) {
+		callCount++
+	})
+
+	target.processConfigurationChanged([]byte(`{"port": "9090"}`))
+	unsubscribe()
+	target.processConfigurationChanged([]byte(`{"port": "7070"}`))
+
+	if callCount != 1 {
+		t.Errorf("expected 1 call before unsubscribing and got %d", callCount)
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	testCases := []struct {
+		pattern  []string
+		name     []string
+		expected bool
+	}{
+		{[]string{"influx", "url"}, []string{"influx", "url"}, true},
+		{[]string{"influx", "url"}, []string{"influx", "database"}, false},
+		{[]string{"influx"}, []string{"influx", "url"}, false},
+		{[]string{"influx", "*"}, []string{"influx", "url"}, true},
+		{[]string{"influx", "*"}, []string{"influx", "pool", "size"}, true},
+		{[]string{"influx", "*"}, []string{"influx"}, false},
+		{[]string{"*", "url"}, []string{"influx", "url"}, true},
+		{[]string{"*", "url"}, []string{"influx", "pool", "url"}, false},
+		{[]string{"db*"}, []string{"dbPoolSize"}, true},
+	}
+
+	for _, testCase := range testCases {
+		if actual := matchPattern(testCase.pattern, testCase.name); actual != testCase.expected {
+			t.Errorf("matchPattern(%v, %v) = %v, expected %v", testCase.pattern, testCase.name, actual, testCase.expected)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxExactInteger bounds the integers read from the document, which are decoded as float64 and so can't tell
+// apart the integers from 2^53 up
+const maxExactInteger = 1 << 53
+
+var byteSizePattern = regexp.MustCompile(`^([0-9]*\.?[0-9]+)\s*([a-zA-Z]*)$`)
+
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1000,
+	"mb":  1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+func (config *Configuration) GetInt64(path string) (int64, error) {
+	item, fromEnv, err := config.lookup(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if fromEnv {
+		intValue, err := strconv.ParseInt(item.(string), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("unable to convert value for '%s' to an int64: Value='%v'", path, config.redact(item))
+		}
+
+		return intValue, nil
+	}
+
+	value, ok := item.(float64)
+	if !ok || value != math.Trunc(value) || math.Abs(value) >= maxExactInteger {
+		return 0, fmt.Errorf("unable to convert value for '%s' to an int64: Value='%v'", path, config.redact(item))
+	}
+
+	return int64(value), nil
+}
+
+func (config *Configuration) GetUint(path string) (uint, error) {
+	item, fromEnv, err := config.lookup(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if fromEnv {
+		uintValue, err := strconv.ParseUint(item.(string), 10, 0)
+		if err != nil {
+			return 0, fmt.Errorf("unable to convert value for '%s' to a uint: Value='%v'", path, config.redact(item))
+		}
+
+		return uint(uintValue), nil
+	}
+
+	value, ok := item.(float64)
+	if !ok || value < 0 || value != math.Trunc(value) || value >= maxExactInteger {
+		return 0, fmt.Errorf("unable to convert value for '%s' to a uint: Value='%v'", path, config.redact(item))
+	}
+
+	return uint(value), nil
+}
+
+// GetDuration returns a duration written as a Go duration string, such as "30s" or "1h15m"
+func (config *Configuration) GetDuration(path string) (time.Duration, error) {
+	item, _, err := config.lookup(path)
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := item.(string)
+	if !ok {
+		return 0, fmt.Errorf("unable to convert value for '%s' to a duration: Value='%v'", path, config.redact(item))
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("unable to convert value for '%s' to a duration: Value='%v'", path, config.redact(item))
+	}
+
+	return duration, nil
+}
+
+// GetTime returns a time written as an RFC 3339 string or as a number of milliseconds since the epoch
+func (config *Configuration) GetTime(path string) (time.Time, error) {
+	item, fromEnv, err := config.lookup(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if fromEnv {
+		if milliseconds, err := strconv.ParseInt(item.(string), 10, 64); err == nil {
+			return millisecondsToTime(milliseconds), nil
+		}
+	}
+
+	switch value := item.(type) {
+	case float64:
+		return millisecondsToTime(int64(value)), nil
+	case string:
+		timeValue, err := time.Parse(time.RFC3339, value)
+		if err == nil {
+			return timeValue, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unable to convert value for '%s' to a time: Value='%v'", path, config.redact(item))
+}
+
+func (config *Configuration) GetURL(path string) (*url.URL, error) {
+	item, _, err := config.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := item.(string)
+	if !ok {
+		return nil, fmt.Errorf("unable to convert value for '%s' to a URL: Value='%v'", path, config.redact(item))
+	}
+
+	urlValue, err := url.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert value for '%s' to a URL: %s", path, err.Error())
+	}
+
+	return urlValue, nil
+}
+
+// GetByteSize returns a number of bytes written as a plain number or with a unit, such as "512KiB" or "10MB".
+// KB, MB, GB and TB are powers of 1000 and KiB, MiB, GiB and TiB are powers of 1024. Units are not case sensitive.
+func (config *Configuration) GetByteSize(path string) (uint64, error) {
+	item, _, err := config.lookup(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var size float64
+	switch value := item.(type) {
+	case float64:
+		size = value
+	case string:
+		size, err = parseByteSize(value)
+		if err != nil {
+			return 0, fmt.Errorf("unable to convert value for '%s' to a byte size: Value='%v'", path, config.redact(item))
+		}
+	default:
+		return 0, fmt.Errorf("unable to convert value for '%s' to a byte size: Value='%v'", path, config.redact(item))
+	}
+
+	if size < 0 || size != math.Trunc(size) || size >= math.MaxUint64 {
+		return 0, fmt.Errorf("unable to convert value for '%s' to a byte size: Value='%v'", path, config.redact(item))
+	}
+
+	return uint64(size), nil
+}
+
+func (config *Configuration) GetIntSlice(path string) ([]int, error) {
+	floatSlice, err := config.GetFloatSlice(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var intSlice []int
+	for _, value := range floatSlice {
+		if value != math.Trunc(value) {
+			return nil, fmt.Errorf("unable to convert a value for '%s' to an int: Value='%v'", path, config.redact(value))
+		}
+		intSlice = append(intSlice, int(value))
+	}
+
+	return intSlice, nil
+}
+
+func (config *Configuration) GetFloatSlice(path string) ([]float64, error) {
+	item, fromEnv, err := config.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromEnv {
+		if items, ok := jsonArray(item.(string)); ok {
+			item, fromEnv = items, false
+		}
+	}
+
+	var floatSlice []float64
+	if fromEnv {
+		for _, sliceItem := range splitEnvList(item.(string)) {
+			value, err := strconv.ParseFloat(sliceItem, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unable to convert a value for '%s' to a float: Value='%v'", path, config.redact(sliceItem))
+			}
+			floatSlice = append(floatSlice, value)
+		}
+
+		return floatSlice, nil
+	}
+
+	slice, ok := item.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unable to convert value for '%s' to a slice: Value='%v'", path, config.redact(item))
+	}
+
+	for _, sliceItem := range slice {
+		value, ok := sliceItem.(float64)
+		if !ok {
+			return nil, fmt.Errorf("unable to convert a value for '%s' to a float: Value='%v'", path, config.redact(sliceItem))
+		}
+		floatSlice = append(floatSlice, value)
+	}
+
+	return floatSlice, nil
+}
+
+// GetStringMap returns a JSON object. An environment variable must hold the object as JSON text.
+func (config *Configuration) GetStringMap(path string) (map[string]interface{}, error) {
+	item, fromEnv, err := config.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromEnv {
+		var value map[string]interface{}
+		if err := json.Unmarshal([]byte(item.(string)), &value); err != nil {
+			return nil, fmt.Errorf("unable to convert value for '%s' to a map[string]interface: Value='%v'", path, config.redact(item))
+		}
+
+		return value, nil
+	}
+
+	value, ok := item.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unable to convert value for '%s' to a map[string]interface: Value='%v'", path, config.redact(item))
+	}
+
+	return value, nil
+}
+
+// GetStringMapString returns a JSON object whose values are strings, numbers or bools, each converted to a string
+func (config *Configuration) GetStringMapString(path string) (map[string]string, error) {
+	mapOfInterface, err := config.GetStringMap(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return interfaceToString(mapOfInterface)
+}
+
+func millisecondsToTime(milliseconds int64) time.Time {
+	return time.Unix(0, milliseconds*int64(time.Millisecond))
+}
+
+func parseByteSize(value string) (float64, error) {
+	matches := byteSizePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		return 0, fmt.Errorf("invalid byte size %s", value)
+	}
+
+	multiplier, ok := byteSizeUnits[strings.ToLower(matches[2])]
+	if !ok {
+		return 0, fmt.Errorf("unknown byte size unit %s", matches[2])
+	}
+
+	number, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return number * multiplier, nil
+}
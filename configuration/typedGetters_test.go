@@ -0,0 +1,289 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadTypedConfig(t *testing.T) *Configuration {
+	target := &Configuration{}
+	if err := target.Load("./testData/typedConfig.json"); err != nil {
+		t.Fatalf("Config file not loaded: %s", err.Error())
+	}
+	return target
+}
+
+func TestGetDuration(t *testing.T) {
+	target := loadTypedConfig(t)
+
+	actual, err := target.GetDuration("timeout")
+	if err != nil {
+		t.Fatalf("GetDuration returned error for 'timeout': %s", err.Error())
+	}
+
+	if actual != time.Second*90 {
+		t.Errorf("Value for 'timeout' is incorrect. Expected='%v', Actual='%v'", time.Second*90, actual)
+	}
+
+	if _, err := target.GetDuration("badTimeout"); err == nil || !strings.Contains(err.Error(), "unable to convert") {
+		t.Error("Expected error not returned")
+	}
+
+	if _, err := target.GetDuration("bogus"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Error("Expected error not returned")
+	}
+}
+
+func TestGetDurationEnv(t *testing.T) {
+	key := "UNIT_TEST_DURATION"
+	target := loadTypedConfig(t)
+
+	os.Setenv(key, "250ms")
+	defer os.Unsetenv(key)
+
+	actual, err := target.GetDuration(key)
+	if err != nil {
+		t.Fatalf("GetDuration returned error for '%s' environment variable: %s", key, err.Error())
+	}
+
+	if actual != time.Millisecond*250 {
+		t.Errorf("Environment variable '%s' incorrect: Expected='%v', Actual='%v'", key, time.Millisecond*250, actual)
+	}
+}
+
+func TestGetInt64(t *testing.T) {
+	target := loadTypedConfig(t)
+
+	actual, err := target.GetInt64("bigNumber")
+	if err != nil {
+		t.Fatalf("GetInt64 returned error for 'bigNumber': %s", err.Error())
+	}
+
+	if actual != 9007199254740991 {
+		t.Errorf("Value for 'bigNumber' is incorrect. Expected='%d', Actual='%d'", int64(9007199254740991), actual)
+	}
+
+	if _, err := target.GetInt64("fraction"); err == nil || !strings.Contains(err.Error(), "unable to convert") {
+		t.Error("Expected error not returned")
+	}
+
+	// Rounded to 9007199254740992 when decoded, so the value can't be returned exactly
+	if _, err := target.GetInt64("tooBigNumber"); err == nil || !strings.Contains(err.Error(), "unable to convert") {
+		t.Error("Expected error not returned for a value past 2^53")
+	}
+}
+
+func TestGetUint(t *testing.T) {
+	target := loadTypedConfig(t)
+
+	actual, err := target.GetUint("plainSize")
+	if err != nil {
+		t.Fatalf("GetUint returned error for 'plainSize': %s", err.Error())
+	}
+
+	if actual != 4096 {
+		t.Errorf("Value for 'plainSize' is incorrect. Expected='%d', Actual='%d'", 4096, actual)
+	}
+
+	if _, err := target.GetUint("negative"); err == nil || !strings.Contains(err.Error(), "unable to convert") {
+		t.Error("Expected error not returned")
+	}
+
+	key := "UNIT_TEST_UINT"
+	os.Setenv(key, "-1")
+	defer os.Unsetenv(key)
+
+	if _, err := target.GetUint(key); err == nil || !strings.Contains(err.Error(), "unable to convert") {
+		t.Error("Expected error not returned")
+	}
+}
+
+func TestGetTime(t *testing.T) {
+	target := loadTypedConfig(t)
+	expected := time.Date(2019, 6, 1, 10, 30, 0, 0, time.UTC)
+
+	actual, err := target.GetTime("startTime")
+	if err != nil {
+		t.Fatalf("GetTime returned error for 'startTime': %s", err.Error())
+	}
+
+	if !actual.Equal(expected) {
+		t.Errorf("Value for 'startTime' is incorrect. Expected='%v', Actual='%v'", expected, actual)
+	}
+
+	actual, err = target.GetTime("startTimeMillis")
+	if err != nil {
+		t.Fatalf("GetTime returned error for 'startTimeMillis': %s", err.Error())
+	}
+
+	if !actual.Equal(expected) {
+		t.Errorf("Value for 'startTimeMillis' is incorrect. Expected='%v', Actual='%v'", expected, actual)
+	}
+
+	if _, err := target.GetTime("timeout"); err == nil || !strings.Contains(err.Error(), "unable to convert") {
+		t.Error("Expected error not returned")
+	}
+}
+
+func TestGetURL(t *testing.T) {
+	target := loadTypedConfig(t)
+
+	actual, err := target.GetURL("influxUrl")
+	if err != nil {
+		t.Fatalf("GetURL returned error for 'influxUrl': %s", err.Error())
+	}
+
+	if actual.Host != "influxdb:8086" || actual.Query().Get("db") != "inventory" {
+		t.Errorf("Value for 'influxUrl' is incorrect: Actual='%v'", actual)
+	}
+
+	if _, err := target.GetURL("plainSize"); err == nil || !strings.Contains(err.Error(), "unable to convert") {
+		t.Error("Expected error not returned")
+	}
+}
+
+func TestGetByteSize(t *testing.T) {
+	target := loadTypedConfig(t)
+
+	testCases := map[string]uint64{
+		"cacheSize":  10 * 1000 * 1000,
+		"bufferSize": 512 * 1024,
+		"plainSize":  4096,
+	}
+
+	for path, expected := range testCases {
+		actual, err := target.GetByteSize(path)
+		if err != nil {
+			t.Errorf("GetByteSize returned error for '%s': %s", path, err.Error())
+			continue
+		}
+
+		if actual != expected {
+			t.Errorf("Value for '%s' is incorrect. Expected='%d', Actual='%d'", path, expected, actual)
+		}
+	}
+
+	key := "UNIT_TEST_BYTE_SIZE"
+	os.Setenv(key, "1.5 GiB")
+	defer os.Unsetenv(key)
+
+	actual, err := target.GetByteSize(key)
+	if err != nil {
+		t.Fatalf("GetByteSize returned error for '%s' environment variable: %s", key, err.Error())
+	}
+
+	if actual != 3*(1<<29) {
+		t.Errorf("Environment variable '%s' incorrect: Expected='%d', Actual='%d'", key, 3*(1<<29), actual)
+	}
+
+	if _, err := target.GetByteSize("timeout"); err == nil || !strings.Contains(err.Error(), "unable to convert") {
+		t.Error("Expected error not returned")
+	}
+
+	if _, err := target.GetByteSize("tooBigSize"); err == nil || !strings.Contains(err.Error(), "unable to convert") {
+		t.Error("Expected error not returned for a size of 2^64")
+	}
+}
+
+func TestGetIntSlice(t *testing.T) {
+	target := loadTypedConfig(t)
+
+	actual, err := target.GetIntSlice("ports")
+	if err != nil {
+		t.Fatalf("GetIntSlice returned error for 'ports': %s", err.Error())
+	}
+
+	expected := []int{8080, 8081, 8082}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Value for 'ports' is incorrect. Expected='%v', Actual='%v'", expected, actual)
+	}
+
+	if _, err := target.GetIntSlice("weights"); err == nil || !strings.Contains(err.Error(), "unable to convert") {
+		t.Error("Expected error not returned")
+	}
+
+	key := "UNIT_TEST_INT_LIST"
+	os.Setenv(key, "[1, 2, 3]")
+	defer os.Unsetenv(key)
+
+	actual, err = target.GetIntSlice(key)
+	if err != nil {
+		t.Fatalf("GetIntSlice returned error for '%s' environment variable: %s", key, err.Error())
+	}
+
+	if !reflect.DeepEqual([]int{1, 2, 3}, actual) {
+		t.Errorf("Environment variable '%s' incorrect: Expected='%v', Actual='%v'", key, []int{1, 2, 3}, actual)
+	}
+}
+
+func TestGetFloatSlice(t *testing.T) {
+	target := loadTypedConfig(t)
+
+	actual, err := target.GetFloatSlice("weights")
+	if err != nil {
+		t.Fatalf("GetFloatSlice returned error for 'weights': %s", err.Error())
+	}
+
+	expected := []float64{0.5, 1.25, 2}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Value for 'weights' is incorrect. Expected='%v', Actual='%v'", expected, actual)
+	}
+
+	if _, err := target.GetFloatSlice("mixed"); err == nil || !strings.Contains(err.Error(), "unable to convert") {
+		t.Error("Expected error not returned")
+	}
+}
+
+func TestGetStringMap(t *testing.T) {
+	target := loadTypedConfig(t)
+
+	actual, err := target.GetStringMap("labels")
+	if err != nil {
+		t.Fatalf("GetStringMap returned error for 'labels': %s", err.Error())
+	}
+
+	expected := map[string]interface{}{"site": "store-1", "floor": float64(2), "active": true}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Value for 'labels' is incorrect. Expected='%v', Actual='%v'", expected, actual)
+	}
+
+	key := "UNIT_TEST_MAP"
+	os.Setenv(key, `{"site": "store-2"}`)
+	defer os.Unsetenv(key)
+
+	actual, err = target.GetStringMap(key)
+	if err != nil {
+		t.Fatalf("GetStringMap returned error for '%s' environment variable: %s", key, err.Error())
+	}
+
+	if !reflect.DeepEqual(map[string]interface{}{"site": "store-2"}, actual) {
+		t.Errorf("Environment variable '%s' incorrect: Actual='%v'", key, actual)
+	}
+}
+
+func TestGetStringMapString(t *testing.T) {
+	target := loadTypedConfig(t)
+
+	actual, err := target.GetStringMapString("labels")
+	if err != nil {
+		t.Fatalf("GetStringMapString returned error for 'labels': %s", err.Error())
+	}
+
+	expected := map[string]string{"site": "store-1", "floor": "2", "active": "true"}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Value for 'labels' is incorrect. Expected='%v', Actual='%v'", expected, actual)
+	}
+
+	if _, err := target.GetStringMapString("nestedLabels"); err == nil {
+		t.Error("Expected error not returned")
+	}
+}
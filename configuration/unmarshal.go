@@ -0,0 +1,398 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	configTag      = "config"
+	defaultTag     = "default"
+	requiredOption = "required"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// FieldError describes a single struct field that could not be bound by Unmarshal
+type FieldError struct {
+	Path    string
+	Field   string
+	Message string
+}
+
+// UnmarshalError aggregates every field that could not be bound by Unmarshal
+type UnmarshalError struct {
+	Fields []FieldError
+}
+
+func (unmarshalError *UnmarshalError) Error() string {
+	messages := make([]string, 0, len(unmarshalError.Fields))
+	for _, field := range unmarshalError.Fields {
+		messages = append(messages, fmt.Sprintf("%s (%s): %s", field.Path, field.Field, field.Message))
+	}
+
+	return fmt.Sprintf("unable to unmarshal configuration: %s", strings.Join(messages, "; "))
+}
+
+// Unmarshal decodes the values of the named section into target, which must be a pointer to a struct.
+// Each field is looked up the same as the Get functions: flags, environment variable overrides, the section,
+// the global keys and then the defaults. Flags and environment variables are parsed for the field's type.
+// An empty section name uses the section of this Configuration.
+//
+// Fields are mapped using the `config:"path"` tag, or the field name with a lower case first letter when
+// the tag is missing. A `config:"path,required"` field that is not found is reported as an error, and a
+// `default:"value"` tag supplies the value of a field that is not found. Nested structs are decoded from the
+// keys beneath their path. All missing and mistyped fields are returned together in an *UnmarshalError.
+func (config *Configuration) Unmarshal(section string, target interface{}) error {
+	targetValue := reflect.ValueOf(target)
+	if targetValue.Kind() != reflect.Ptr || targetValue.IsNil() || targetValue.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unmarshal target must be a non-nil pointer to a struct: Type='%v'", reflect.TypeOf(target))
+	}
+
+	if section == "" {
+		section = config.sectionName
+	}
+
+	// Decode every field from the same document even if an update is applied part way through
+	parsedJson := config.parsedJson()
+
+	unmarshalError := &UnmarshalError{}
+	config.unmarshalStruct(parsedJson, section, "", targetValue.Elem(), unmarshalError)
+
+	if len(unmarshalError.Fields) > 0 {
+		for index := range unmarshalError.Fields {
+			unmarshalError.Fields[index].Message = config.secrets.redact(unmarshalError.Fields[index].Message)
+		}
+		return unmarshalError
+	}
+
+	return nil
+}
+
+func (config *Configuration) unmarshalStruct(parsedJson map[string]interface{}, section string, prefix string, structValue reflect.Value, unmarshalError *UnmarshalError) {
+	structType := structValue.Type()
+
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		if field.PkgPath != "" {
+			// Unexported field
+			continue
+		}
+
+		name, required, skip := parseConfigTag(field)
+		if skip {
+			continue
+		}
+
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		fieldValue := structValue.Field(index)
+		if isNestedStruct(field.Type) {
+			if field.Type.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					fieldValue.Set(reflect.New(field.Type.Elem()))
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			config.unmarshalStruct(parsedJson, section, path, fieldValue, unmarshalError)
+			continue
+		}
+
+		value, source, _, found := config.locateIn(parsedJson, section, path)
+		if text, isText := value.(string); isText && (source == EnvironmentSource || source == FlagSource) {
+			value = parseEnvValue(text, field.Type)
+		}
+		if !found {
+			defaultValue, hasDefault := field.Tag.Lookup(defaultTag)
+			switch {
+			case hasDefault:
+				value = parseDefaultValue(defaultValue, field.Type)
+			case required:
+				unmarshalError.Fields = append(unmarshalError.Fields, FieldError{Path: path, Field: field.Name, Message: "required value not found"})
+				continue
+			default:
+				continue
+			}
+		}
+
+		value, err := config.secrets.resolve(value)
+		if err != nil {
+			unmarshalError.Fields = append(unmarshalError.Fields, FieldError{Path: path, Field: field.Name, Message: fmt.Sprintf("unable to resolve secret: %s", err.Error())})
+			continue
+		}
+
+		if err := decodeValue(value, fieldValue); err != nil {
+			unmarshalError.Fields = append(unmarshalError.Fields, FieldError{Path: path, Field: field.Name, Message: err.Error()})
+		}
+	}
+}
+
+// getSectionValue looks up path in the named section and then in the global keys
+func getSectionValue(parsedJson map[string]interface{}, section string, path string) (interface{}, bool) {
+	if section != "" {
+		if value, found := lookupPath(parsedJson, sectionPath(section, path)); found {
+			return value, true
+		}
+	}
+
+	return lookupPath(parsedJson, path)
+}
+
+func parseConfigTag(field reflect.StructField) (string, bool, bool) {
+	tag := field.Tag.Get(configTag)
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name := strings.TrimSpace(parts[0])
+	if name == "" {
+		name = lowerFirst(field.Name)
+	}
+
+	required := false
+	for _, option := range parts[1:] {
+		if strings.TrimSpace(option) == requiredOption {
+			required = true
+		}
+	}
+
+	return name, required, false
+}
+
+func lowerFirst(name string) string {
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(first)) + name[size:]
+}
+
+func isNestedStruct(fieldType reflect.Type) bool {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	return fieldType.Kind() == reflect.Struct && fieldType != timeType
+}
+
+// parseEnvValue converts a flag or environment variable into the same form as a value parsed from the JSON
+// document, accepting what the Get functions accept: any strconv.ParseBool value for a bool, and a JSON array
+// or comma separated items for a slice
+func parseEnvValue(text string, fieldType reflect.Type) interface{} {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Bool:
+		if boolValue, err := strconv.ParseBool(text); err == nil {
+			return boolValue
+		}
+
+	case reflect.Slice:
+		if items, ok := jsonArray(text); ok {
+			return items
+		}
+
+		var items []interface{}
+		for _, item := range splitEnvList(text) {
+			items = append(items, parseEnvValue(item, fieldType.Elem()))
+		}
+		return items
+	}
+
+	return parseDefaultValue(text, fieldType)
+}
+
+// parseDefaultValue converts the default tag into the same form as a value parsed from the JSON document.
+// Strings are used as is, everything else is parsed as JSON and falls back to the raw string.
+func parseDefaultValue(defaultValue string, fieldType reflect.Type) interface{} {
+	if fieldType.Kind() == reflect.String {
+		return defaultValue
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(defaultValue), &value); err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+// decodeValue stores a value parsed from the JSON document into target, converting it to the target's type
+func decodeValue(value interface{}, target reflect.Value) error {
+	if target.Kind() == reflect.Ptr {
+		element := reflect.New(target.Type().Elem())
+		if err := decodeValue(value, element.Elem()); err != nil {
+			return err
+		}
+		target.Set(element)
+		return nil
+	}
+
+	if target.Type() == durationType {
+		stringValue, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unable to convert value to a duration: Value='%v'", value)
+		}
+		duration, err := time.ParseDuration(stringValue)
+		if err != nil {
+			return fmt.Errorf("unable to convert value to a duration: Value='%v'", value)
+		}
+		target.SetInt(int64(duration))
+		return nil
+	}
+
+	if target.Type() == timeType {
+		stringValue, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unable to convert value to a time: Value='%v'", value)
+		}
+		timeValue, err := time.Parse(time.RFC3339, stringValue)
+		if err != nil {
+			return fmt.Errorf("unable to convert value to a time: Value='%v'", value)
+		}
+		target.Set(reflect.ValueOf(timeValue))
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.String:
+		stringValue, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("unable to convert value to a string: Value='%v'", value)
+		}
+		target.SetString(stringValue)
+
+	case reflect.Bool:
+		boolValue, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("unable to convert value to a bool: Value='%v'", value)
+		}
+		target.SetBool(boolValue)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		floatValue, ok := value.(float64)
+		if !ok || floatValue != float64(int64(floatValue)) || target.OverflowInt(int64(floatValue)) {
+			return fmt.Errorf("unable to convert value to an %s: Value='%v'", target.Kind(), value)
+		}
+		target.SetInt(int64(floatValue))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		floatValue, ok := value.(float64)
+		if !ok || floatValue < 0 || floatValue != float64(uint64(floatValue)) || target.OverflowUint(uint64(floatValue)) {
+			return fmt.Errorf("unable to convert value to a %s: Value='%v'", target.Kind(), value)
+		}
+		target.SetUint(uint64(floatValue))
+
+	case reflect.Float32, reflect.Float64:
+		floatValue, ok := value.(float64)
+		if !ok || target.OverflowFloat(floatValue) {
+			return fmt.Errorf("unable to convert value to a %s: Value='%v'", target.Kind(), value)
+		}
+		target.SetFloat(floatValue)
+
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("unable to convert value to a slice: Value='%v'", value)
+		}
+		slice := reflect.MakeSlice(target.Type(), len(items), len(items))
+		for index, item := range items {
+			if err := decodeValue(item, slice.Index(index)); err != nil {
+				return fmt.Errorf("item %d: %s", index, err.Error())
+			}
+		}
+		target.Set(slice)
+
+	case reflect.Map:
+		if target.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map key type %s", target.Type().Key())
+		}
+		items, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unable to convert value to a map: Value='%v'", value)
+		}
+		mapValue := reflect.MakeMapWithSize(target.Type(), len(items))
+		for key, item := range items {
+			element := reflect.New(target.Type().Elem()).Elem()
+			if err := decodeValue(item, element); err != nil {
+				return fmt.Errorf("key '%s': %s", key, err.Error())
+			}
+			mapValue.SetMapIndex(reflect.ValueOf(key).Convert(target.Type().Key()), element)
+		}
+		target.Set(mapValue)
+
+	case reflect.Struct:
+		items, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unable to convert value to a struct: Value='%v'", value)
+		}
+		return decodeStruct(items, target)
+
+	case reflect.Interface:
+		if value == nil {
+			return nil
+		}
+		if !reflect.TypeOf(value).AssignableTo(target.Type()) {
+			return fmt.Errorf("unable to convert value to %s: Value='%v'", target.Type(), value)
+		}
+		target.Set(reflect.ValueOf(value))
+
+	default:
+		return fmt.Errorf("unsupported field type %s", target.Type())
+	}
+
+	return nil
+}
+
+// decodeStruct decodes a struct held inside a slice or map, where there is no section or global fallback
+func decodeStruct(items map[string]interface{}, target reflect.Value) error {
+	targetType := target.Type()
+	for index := 0; index < targetType.NumField(); index++ {
+		field := targetType.Field(index)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name, required, skip := parseConfigTag(field)
+		if skip {
+			continue
+		}
+
+		value, found := lookupPath(items, name)
+		if !found {
+			defaultValue, hasDefault := field.Tag.Lookup(defaultTag)
+			switch {
+			case hasDefault:
+				value = parseDefaultValue(defaultValue, field.Type)
+			case required:
+				return fmt.Errorf("required value '%s' not found", name)
+			default:
+				continue
+			}
+		}
+
+		if err := decodeValue(value, target.Field(index)); err != nil {
+			return fmt.Errorf("'%s': %s", name, err.Error())
+		}
+	}
+
+	return nil
+}
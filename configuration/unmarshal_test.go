@@ -0,0 +1,266 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type influxSettings struct {
+	Url      string `config:"url,required"`
+	Database string
+	Retries  int `default:"3"`
+}
+
+type readerSettings struct {
+	Host string `config:"host,required"`
+	Port int    `config:"port" default:"5084"`
+}
+
+type inventorySettings struct {
+	ServiceName       string           `config:"serviceName,required"`
+	Port              string           `config:"port"`
+	LoggingLevel      string           `config:"loggingLevel"`
+	EpcFilters        []string         `config:"epcFilters"`
+	AgeOuts           map[string]int   `config:"ageOuts"`
+	ReadTimeout       time.Duration    `config:"serverReadTimeOut"`
+	WriteTimeout      time.Duration    `config:"serverWriteTimeOut" default:"30s"`
+	Partition         int              `config:"contraEpcPartition"`
+	TriggerRules      bool             `config:"triggerRulesOnFixedTags"`
+	ResponseLimit     int              `config:"responseLimit" default:"10000"`
+	Influx            influxSettings   `config:"influx"`
+	Readers           []readerSettings `config:"readers"`
+	Ignored           string           `config:"-"`
+	unexportedIgnored string
+}
+
+func TestUnmarshalSection(t *testing.T) {
+	target, err := NewConfiguration()
+
+	if err != nil {
+		t.Fatalf("NewConfiguration returned error %s", err.Error())
+	}
+	target.Load("./testData/unmarshalConfig.json")
+
+	var actual inventorySettings
+	if err := target.Unmarshal("inventory-service", &actual); err != nil {
+		t.Fatalf("Unmarshal returned error %s", err.Error())
+	}
+
+	expected := inventorySettings{
+		ServiceName:   "RRP Inventory Service",
+		Port:          "8081",
+		LoggingLevel:  "info",
+		EpcFilters:    []string{"30", "31"},
+		AgeOuts:       map[string]int{"front": 10, "back": 60},
+		ReadTimeout:   time.Second * 15,
+		WriteTimeout:  time.Second * 30,
+		Partition:     5,
+		TriggerRules:  true,
+		ResponseLimit: 10000,
+		Influx: influxSettings{
+			Url:      "http://influxdb:8086",
+			Database: "inventory-service",
+			Retries:  3,
+		},
+		Readers: []readerSettings{
+			{Host: "reader-1", Port: 5084},
+			{Host: "reader-2", Port: 5084},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Unmarshaled settings not as expected.\nExpected='%+v'\nActual='%+v'", expected, actual)
+	}
+}
+
+func TestUnmarshalGlobalOnly(t *testing.T) {
+	target, err := NewConfiguration()
+
+	if err != nil {
+		t.Fatalf("NewConfiguration returned error %s", err.Error())
+	}
+	target.Load("./testData/unmarshalConfig.json")
+
+	var actual struct {
+		Port   string         `config:"port"`
+		Influx influxSettings `config:"influx"`
+	}
+	if err := target.Unmarshal("bogus-service", &actual); err != nil {
+		t.Fatalf("Unmarshal returned error %s", err.Error())
+	}
+
+	if actual.Port != "8080" {
+		t.Errorf("Global port not as expected. Expected='%s', Actual='%s'", "8080", actual.Port)
+	}
+
+	expected := influxSettings{Url: "http://influxdb:8086", Database: "inventory", Retries: 3}
+	if actual.Influx != expected {
+		t.Errorf("Global influx settings not as expected. Expected='%+v', Actual='%+v'", expected, actual.Influx)
+	}
+}
+
+func TestUnmarshalAggregatedErrors(t *testing.T) {
+	target, err := NewConfiguration()
+
+	if err != nil {
+		t.Fatalf("NewConfiguration returned error %s", err.Error())
+	}
+	target.Load("./testData/unmarshalConfig.json")
+
+	var actual struct {
+		ServiceName string   `config:"serviceName,required"`
+		Partition   int      `config:"contraEpcPartition"`
+		EpcFilters  []string `config:"epcFilters"`
+		Missing     string   `config:"missing,required"`
+	}
+
+	err = target.Unmarshal("bad-types-service", &actual)
+	if err == nil {
+		t.Fatal("Expected an error, but didn't get it")
+	}
+
+	unmarshalError, ok := err.(*UnmarshalError)
+	if !ok {
+		t.Fatalf("Expected an *UnmarshalError, but got %T", err)
+	}
+
+	if len(unmarshalError.Fields) != 4 {
+		t.Fatalf("Expected 4 field errors, but got %d: %s", len(unmarshalError.Fields), err.Error())
+	}
+
+	for _, path := range []string{"serviceName", "contraEpcPartition", "epcFilters", "missing"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("Expected error to mention '%s': %s", path, err.Error())
+		}
+	}
+}
+
+func TestUnmarshalInterfaceFields(t *testing.T) {
+	target, err := NewConfiguration()
+
+	if err != nil {
+		t.Fatalf("NewConfiguration returned error %s", err.Error())
+	}
+	target.Load("./testData/unmarshalConfig.json")
+
+	var actual struct {
+		ServiceName fmt.Stringer `config:"serviceName"`
+		Port        interface{}  `config:"port"`
+	}
+
+	err = target.Unmarshal("inventory-service", &actual)
+
+	unmarshalError, ok := err.(*UnmarshalError)
+	if !ok {
+		t.Fatalf("Expected an *UnmarshalError, but got %v", err)
+	}
+
+	if len(unmarshalError.Fields) != 1 || unmarshalError.Fields[0].Field != "ServiceName" {
+		t.Errorf("Expected a field error for ServiceName only, got %s", err.Error())
+	}
+
+	if actual.Port != "8081" {
+		t.Errorf("Port not as expected. Expected='8081', Actual='%v'", actual.Port)
+	}
+}
+
+func TestUnmarshalRequiredNested(t *testing.T) {
+	target, err := NewConfiguration()
+
+	if err != nil {
+		t.Fatalf("NewConfiguration returned error %s", err.Error())
+	}
+	target.Load("./testData/unmarshalConfig.json")
+
+	var actual struct {
+		Readers []struct {
+			Host string `config:"host"`
+			Port int    `config:"port,required"`
+		} `config:"readers"`
+	}
+
+	err = target.Unmarshal("inventory-service", &actual)
+	if err == nil || !strings.Contains(err.Error(), "readers") || !strings.Contains(err.Error(), "port") {
+		t.Errorf("Expected required error for readers port, got %v", err)
+	}
+}
+
+func TestUnmarshalOverridesAndDefaults(t *testing.T) {
+	environment := map[string]string{
+		"UNMARSHAL_TEST_PORT":                         "9090",
+		"UNMARSHAL_TEST_INVENTORY_SERVICE_EPCFILTERS": "32, 33",
+		"UNMARSHAL_TEST_TRIGGERRULESONFIXEDTAGS":      "1",
+		"UNMARSHAL_TEST_INFLUX_RETRIES":               "5",
+	}
+	for name, value := range environment {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	target := &Configuration{sectionName: "inventory-service", envPrefix: "UNMARSHAL_TEST"}
+	target.processConfigurationChanged([]byte(`{"port": "8080", "influx": {"url": "http://influxdb:8086"}, "inventory-service": {"epcFilters": ["30"]}}`))
+	if err := target.SetDefaults(map[string]interface{}{"serviceName": "Default Inventory Service", "responseLimit": 500}); err != nil {
+		t.Fatalf("SetDefaults returned error %s", err.Error())
+	}
+
+	var actual struct {
+		ServiceName   string         `config:"serviceName,required"`
+		Port          int            `config:"port"`
+		EpcFilters    []string       `config:"epcFilters"`
+		TriggerRules  bool           `config:"triggerRulesOnFixedTags"`
+		ResponseLimit int            `config:"responseLimit" default:"10000"`
+		Influx        influxSettings `config:"influx"`
+	}
+	if err := target.Unmarshal("", &actual); err != nil {
+		t.Fatalf("Unmarshal returned error %s", err.Error())
+	}
+
+	if expected, _ := target.GetInt("port"); actual.Port != expected || actual.Port != 9090 {
+		t.Errorf("port not as expected. Expected='9090' as returned by GetInt, Actual='%d'", actual.Port)
+	}
+
+	if !reflect.DeepEqual(actual.EpcFilters, []string{"32", "33"}) {
+		t.Errorf("epcFilters not as expected. Expected='[32 33]', Actual='%v'", actual.EpcFilters)
+	}
+
+	if !actual.TriggerRules {
+		t.Error("triggerRulesOnFixedTags not as expected. Expected='true', Actual='false'")
+	}
+
+	if actual.Influx.Retries != 5 {
+		t.Errorf("influx.retries not as expected. Expected='5', Actual='%d'", actual.Influx.Retries)
+	}
+
+	// A registered default is used before the default tag
+	if actual.ServiceName != "Default Inventory Service" || actual.ResponseLimit != 500 {
+		t.Errorf("Defaults not as expected. Expected='Default Inventory Service' and '500', Actual='%s' and '%d'", actual.ServiceName, actual.ResponseLimit)
+	}
+}
+
+func TestUnmarshalBadTarget(t *testing.T) {
+	target, err := NewConfiguration()
+
+	if err != nil {
+		t.Fatalf("NewConfiguration returned error %s", err.Error())
+	}
+
+	var settings inventorySettings
+	if err := target.Unmarshal("inventory-service", settings); err == nil {
+		t.Error("Expected error for non pointer target")
+	}
+
+	var notStruct string
+	if err := target.Unmarshal("inventory-service", &notStruct); err == nil {
+		t.Error("Expected error for non struct target")
+	}
+}
@@ -0,0 +1,362 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/consulApi"
+)
+
+// ConflictError is returned by Set and Delete when the configuration in Consul was changed by someone else
+// after ModifyIndex, the last version this Configuration read
+type ConflictError struct {
+	Key         string
+	ModifyIndex uint64
+}
+
+func (conflictError *ConflictError) Error() string {
+	return fmt.Sprintf("configuration in Consul key '%s' was changed after index %d by another update, reload and try again", conflictError.Key, conflictError.ModifyIndex)
+}
+
+// Set changes the value at path, the full path in the document such as "inventory-service.port", and
+// writes the configuration back to Consul. The write is a check-and-set against the last version read, so a
+// *ConflictError is returned rather than overwriting a change made by someone else. The change is applied
+// and the callbacks notified before Set returns, except when Set is called from a callback or subscription,
+// which is notified of the change once it returns. Set returns an error when called from a change handler,
+// since the update being handled can't be followed by another until the handlers return. The change
+// handlers are called before the write, and when one rejects the change a *ChangeRejectedError is returned
+// and nothing is written to Consul.
+//
+// With Options.ConsulTree only the key for path is written, and its value can't be an object. An array is the
+// value of a single key, so setting an element such as "epcFilters[0]" writes the whole array. Otherwise the
+// whole document is written back as JSON.
+func (config *Configuration) Set(path string, value interface{}) error {
+	normalizedValue, err := normalizeValue(value)
+	if err != nil {
+		return fmt.Errorf("unable to set '%s': %s", path, err.Error())
+	}
+
+	return config.writeBack(path, func(document map[string]interface{}) error {
+		if err := setPath(document, path, normalizedValue); err != nil {
+			return fmt.Errorf("unable to set '%s': %s", path, err.Error())
+		}
+		return nil
+	}, func(snapshot *configSnapshot, document map[string]interface{}) error {
+		if arrayPath, inArray := treeArrayPath(document, path); inArray {
+			array, _ := lookupPath(document, arrayPath)
+			return config.putTreeValue(snapshot, arrayPath, array)
+		}
+
+		if object, ok := normalizedValue.(map[string]interface{}); ok && len(object) > 0 {
+			return fmt.Errorf("unable to set '%s': objects can't be set in a Consul key tree, set each key beneath it instead", path)
+		}
+
+		return config.putTreeValue(snapshot, path, normalizedValue)
+	})
+}
+
+// Delete removes the value at path, the full path in the document, and writes the configuration back
+// to Consul the same way as Set. With Options.ConsulTree deleting an object deletes every key beneath it.
+func (config *Configuration) Delete(path string) error {
+	return config.writeBack(path, func(document map[string]interface{}) error {
+		if !deletePath(document, path) {
+			return &notFoundError{path: path}
+		}
+		return nil
+	}, func(snapshot *configSnapshot, document map[string]interface{}) error {
+		if arrayPath, inArray := treeArrayPath(document, path); inArray {
+			array, _ := lookupPath(document, arrayPath)
+			return config.putTreeValue(snapshot, arrayPath, array)
+		}
+
+		key := config.treeKey(path)
+
+		value, _ := lookupPath(snapshot.document, path)
+		if _, isObject := value.(map[string]interface{}); isObject {
+			prefix := treePrefix(key)
+			keyValuePairs, _, err := config.consul.ListValues(prefix, nil)
+			if err != nil {
+				return err
+			}
+
+			// A recursive delete can't be a check-and-set, so check nothing beneath it changed first
+			for _, keyValuePair := range keyValuePairs {
+				if keyValuePair.ModifyIndex > snapshot.modifyIndex {
+					return &ConflictError{Key: keyValuePair.Key, ModifyIndex: snapshot.modifyIndex}
+				}
+			}
+
+			return config.consul.DeleteTree(prefix)
+		}
+
+		modifyIndex, err := config.checkTreeKey(snapshot, path, key)
+		if err != nil {
+			return err
+		}
+
+		ok, err := config.consul.DeleteValueCAS(key, modifyIndex)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &ConflictError{Key: key, ModifyIndex: snapshot.modifyIndex}
+		}
+
+		return nil
+	})
+}
+
+// writeBack applies change to a copy of the document as written and validates the result, which the change
+// handlers must accept before it is written. A single document is then written back to Consul as a whole,
+// while a key tree is written by writeTree. Updates are held back until the write has been read back, so
+// the changes are checked against the configuration they were made to.
+func (config *Configuration) writeBack(path string, change func(map[string]interface{}) error, writeTree func(*configSnapshot, map[string]interface{}) error) error {
+	if config.consul == nil {
+		return fmt.Errorf("unable to write '%s': configuration is not read from Consul", path)
+	}
+
+	// The update being handled holds updateMutex until the handlers return, so the write could never be applied
+	config.mutex.RLock()
+	handlingChanges := config.handlingChanges
+	config.mutex.RUnlock()
+	if handlingChanges {
+		return fmt.Errorf("unable to write '%s': the configuration can't be changed while change handlers are running", path)
+	}
+
+	defer config.deliverNotifications()
+
+	config.updateMutex.Lock()
+	defer config.updateMutex.Unlock()
+
+	snapshot, _ := config.current.Load().(*configSnapshot)
+	if snapshot == nil {
+		snapshot = &configSnapshot{}
+	}
+
+	document := make(map[string]interface{})
+	mergeDocuments(document, snapshot.document)
+	if err := change(document); err != nil {
+		return err
+	}
+
+	// Reject a change that would be rejected when read back from Consul, including by a change handler, so
+	// Consul never holds a configuration this instance refused
+	parsedJson, err := config.prepareDocument(document)
+	if err != nil {
+		return err
+	}
+
+	changedList := config.changesTo(parsedJson)
+	if len(changedList) > 0 {
+		if err := config.callChangeHandlers(changedList); err != nil {
+			return err
+		}
+	}
+
+	if err := config.writeDocument(path, document, snapshot, writeTree); err != nil {
+		if len(changedList) > 0 {
+			config.undoChangeHandlers(changedList)
+		}
+		return err
+	}
+
+	keyValuePair, err := config.readFromConsul()
+	if err != nil || keyValuePair == nil {
+		return err
+	}
+
+	return config.applyChangedPair(keyValuePair, document)
+}
+
+// writeDocument writes the changed document to Consul with a check-and-set against the snapshot it was
+// changed from
+func (config *Configuration) writeDocument(path string, document map[string]interface{}, snapshot *configSnapshot, writeTree func(*configSnapshot, map[string]interface{}) error) error {
+	if config.consulTree {
+		return writeTree(snapshot, document)
+	}
+
+	documentJson, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to write '%s': %s", path, err.Error())
+	}
+
+	ok, err := config.consul.PutValueCAS(config.consulKey, string(documentJson), snapshot.modifyIndex)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &ConflictError{Key: config.consulKey, ModifyIndex: snapshot.modifyIndex}
+	}
+
+	return nil
+}
+
+// putTreeValue writes the value at path to its key with a check-and-set against the snapshot
+func (config *Configuration) putTreeValue(snapshot *configSnapshot, path string, value interface{}) error {
+	valueJson, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("unable to set '%s': %s", path, err.Error())
+	}
+
+	key := config.treeKey(path)
+	modifyIndex, err := config.checkTreeKey(snapshot, path, key)
+	if err != nil {
+		return err
+	}
+
+	ok, err := config.consul.PutValueCAS(key, string(valueJson), modifyIndex)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &ConflictError{Key: key, ModifyIndex: snapshot.modifyIndex}
+	}
+
+	return nil
+}
+
+// treeArrayPath returns the path of the array holding the value at path, such as "epcFilters" for
+// "epcFilters[0]", since a key tree stores a whole array as the value of one key
+func treeArrayPath(document map[string]interface{}, path string) (string, bool) {
+	segments, err := splitPath(path)
+	if err != nil {
+		return "", false
+	}
+
+	arrayPath := ""
+	object := document
+	for _, segment := range segments[:len(segments)-1] {
+		arrayPath = joinPath(arrayPath, segment)
+		switch child := object[segment].(type) {
+		case []interface{}:
+			return arrayPath, true
+		case map[string]interface{}:
+			object = child
+		default:
+			return "", false
+		}
+	}
+
+	return "", false
+}
+
+// checkTreeKey returns the ModifyIndex to check-and-set the key with, or a *ConflictError if the key was
+// added, changed or deleted since the snapshot was read
+func (config *Configuration) checkTreeKey(snapshot *configSnapshot, path string, key string) (uint64, error) {
+	keyValuePair, err := config.consul.GetValue(key, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if keyValuePair == nil {
+		if _, found := lookupPath(snapshot.document, path); found {
+			return 0, &ConflictError{Key: key, ModifyIndex: snapshot.modifyIndex}
+		}
+		return 0, nil
+	}
+
+	if keyValuePair.ModifyIndex > snapshot.modifyIndex {
+		return 0, &ConflictError{Key: key, ModifyIndex: snapshot.modifyIndex}
+	}
+
+	return keyValuePair.ModifyIndex, nil
+}
+
+func (config *Configuration) treeKey(path string) string {
+	segments, err := splitPath(path)
+	if err != nil {
+		return treePrefix(config.consulKey) + path
+	}
+
+	return treePrefix(config.consulKey) + strings.Join(segments, treeSeparator)
+}
+
+// refreshFromConsul reads the configuration from Consul and applies it without waiting for the watcher, and
+// returns the error when the configuration was rejected
+func (config *Configuration) refreshFromConsul() error {
+	keyValuePair, err := config.readFromConsul()
+	if err != nil || keyValuePair == nil {
+		return err
+	}
+
+	return config.applyKeyValuePair(keyValuePair)
+}
+
+// readFromConsul reads the configuration, or nil when the key isn't in Consul. A key tree is read as a single
+// document.
+func (config *Configuration) readFromConsul() (*consulApi.KeyValuePair, error) {
+	if config.consulTree {
+		prefix := treePrefix(config.consulKey)
+		keyValuePairs, queryMeta, err := config.consul.ListValues(prefix, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		documentJson, err := treeDocumentJson(prefix, keyValuePairs)
+		if err != nil {
+			return nil, err
+		}
+
+		return &consulApi.KeyValuePair{Key: prefix, Value: documentJson, ModifyIndex: queryMeta.LastIndex}, nil
+	}
+
+	return config.consul.GetValue(config.consulKey, nil)
+}
+
+// deletePath removes the value at the path and returns whether it was found. Removing an array element moves
+// the elements after it down.
+func deletePath(document map[string]interface{}, path string) bool {
+	segments, err := splitPath(path)
+	if err != nil {
+		return false
+	}
+
+	_, found := deleteChild(document, segments)
+	return found
+}
+
+// deleteChild removes the value at the segments beneath parent and returns parent as changed, which is a new
+// array when an element was removed from it
+func deleteChild(parent interface{}, segments []string) (interface{}, bool) {
+	if len(segments) > 1 {
+		existing, found := getChild(parent, segments[0])
+		if !found {
+			return parent, false
+		}
+
+		child, isContainer := copyContainer(existing)
+		if !isContainer {
+			return parent, false
+		}
+
+		child, found = deleteChild(child, segments[1:])
+		if !found {
+			return parent, false
+		}
+
+		return parent, setChild(parent, segments[0], child) == nil
+	}
+
+	if _, found := getChild(parent, segments[0]); !found {
+		return parent, false
+	}
+
+	switch typed := parent.(type) {
+	case map[string]interface{}:
+		delete(typed, segments[0])
+		return typed, true
+	case []interface{}:
+		index, _ := strconv.Atoi(segments[0])
+		return append(typed[:index:index], typed[index+1:]...), true
+	}
+
+	return parent, false
+}
@@ -0,0 +1,261 @@
+/* Apache v2 license
+*  Copyright (C) <2019> Intel Corporation
+*
+*  SPDX-License-Identifier: Apache-2.0
+ */
+
+package configuration
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/intel/rsp-sw-toolkit-im-suite-utilities/consulApi"
+)
+
+// newWriteBackConfig returns a Configuration read from Consul without a watcher, so it only sees the
+// changes it writes itself
+func newWriteBackConfig(t *testing.T, tree bool) (*Configuration, *consulApi.Client, string) {
+	consul, err := consulApi.NewClient(&consulApi.Config{Address: consulUrl})
+	if err != nil {
+		t.Fatalf("not able to communicate with Consul service at %s: %s", consulUrl, err.Error())
+	}
+
+	key := "config/unit-test-write-back-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	layers := []string{"./testData/layered/base.json"}
+
+	var keyValuePair *consulApi.KeyValuePair
+	if tree {
+		keyValuePair, err = checkAndUpdateTreeFromLocal(consul, key, layers)
+	} else {
+		keyValuePair, err = checkAndUpdateFromLocal(consul, key, layers)
+	}
+	if err != nil {
+		t.Fatalf("unable to push configuration to Consul: %s", err.Error())
+	}
+
+	target := &Configuration{sectionName: "inventory-service", consul: consul, consulKey: key, consulTree: tree}
+	if err := target.applyConfigurationJson(keyValuePair); err != nil {
+		t.Fatalf("applyConfigurationJson returned error %s", err.Error())
+	}
+
+	return target, consul, key
+}
+
+func TestSetAndDelete(t *testing.T) {
+	for _, tree := range []bool{false, true} {
+		target, _, _ := newWriteBackConfig(t, tree)
+
+		var actualChanges []ChangeDetails
+		target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+			actualChanges = changes
+		})
+
+		if err := target.Set("inventory-service.serviceName", "Site Inventory Service"); err != nil {
+			t.Fatalf("Set returned error %s", err.Error())
+		}
+
+		if actual, _ := target.GetString("serviceName"); actual != "Site Inventory Service" {
+			t.Errorf("serviceName not as expected after Set. Expected='Site Inventory Service', Actual='%s'", actual)
+		}
+
+		if len(actualChanges) != 1 || actualChanges[0].Name != "inventory-service.serviceName" {
+			t.Errorf("Expected change notification for serviceName, got %v", actualChanges)
+		}
+
+		// Set is based on the version it wrote, so writes can follow each other
+		if err := target.Set("responseLimit", 500); err != nil {
+			t.Fatalf("Set returned error %s", err.Error())
+		}
+
+		if actual, _ := target.GetInt("responseLimit"); actual != 500 {
+			t.Errorf("responseLimit not as expected after Set. Expected='500', Actual='%d'", actual)
+		}
+
+		if err := target.Delete("inventory-service.debugEndpoint"); err != nil {
+			t.Fatalf("Delete returned error %s", err.Error())
+		}
+
+		if _, err := target.GetString("debugEndpoint"); !isNotFound(err) {
+			t.Errorf("Expected debugEndpoint to be deleted, got %v", err)
+		}
+
+		if err := target.Delete("influx"); err != nil {
+			t.Fatalf("Delete of object returned error %s", err.Error())
+		}
+
+		if _, err := target.GetString("influx.url"); !isNotFound(err) {
+			t.Errorf("Expected influx.url to be deleted, got %v", err)
+		}
+
+		if err := target.Delete("bogus"); !isNotFound(err) {
+			t.Errorf("Expected not found error for Delete of missing key, got %v", err)
+		}
+	}
+}
+
+func TestSetArrayElement(t *testing.T) {
+	for _, tree := range []bool{false, true} {
+		target, consul, key := newWriteBackConfig(t, tree)
+
+		if err := target.Set("inventory-service.epcFilters[0]", "99"); err != nil {
+			t.Fatalf("Set of array element returned error %s", err.Error())
+		}
+
+		if err := target.Delete("inventory-service.epcFilters[1]"); err != nil {
+			t.Fatalf("Delete of array element returned error %s", err.Error())
+		}
+
+		expected := []interface{}{"99"}
+		if actual, _ := target.GetNestedJSON("inventory-service"); !reflect.DeepEqual(expected, actual["epcFilters"]) {
+			t.Errorf("epcFilters not as expected. Expected='%v', Actual='%v'", expected, actual["epcFilters"])
+		}
+
+		// A key tree holds the whole array in the key of the array
+		if tree {
+			keyValuePair, err := consul.GetValue(key+"/inventory-service/epcFilters", nil)
+			if err != nil || keyValuePair == nil || string(keyValuePair.Value) != `["99"]` {
+				t.Errorf("Expected the array to be written to its key, got %v %v", keyValuePair, err)
+			}
+		}
+	}
+}
+
+func TestSetConflict(t *testing.T) {
+	target, consul, key := newWriteBackConfig(t, false)
+
+	// Someone else changes the configuration after it was read
+	if err := consul.PutValue(key, `{"port": "9090"}`); err != nil {
+		t.Fatalf("PutValue returned error %s", err.Error())
+	}
+
+	err := target.Set("port", "8085")
+	if _, ok := err.(*ConflictError); !ok {
+		t.Fatalf("Expected a *ConflictError, got %v", err)
+	}
+
+	keyValuePair, _ := consul.GetValue(key, nil)
+	if keyValuePair == nil || string(keyValuePair.Value) != `{"port": "9090"}` {
+		t.Errorf("Expected the concurrent change to be kept, got %v", keyValuePair)
+	}
+}
+
+func TestSetConflictTree(t *testing.T) {
+	target, consul, key := newWriteBackConfig(t, true)
+
+	if err := consul.PutValue(key+"/port", `"9090"`); err != nil {
+		t.Fatalf("PutValue returned error %s", err.Error())
+	}
+
+	if _, ok := target.Set("port", "8085").(*ConflictError); !ok {
+		t.Error("Expected a *ConflictError for a changed key")
+	}
+
+	if err := consul.PutValue(key+"/influx/password", `"secret"`); err != nil {
+		t.Fatalf("PutValue returned error %s", err.Error())
+	}
+
+	if _, ok := target.Delete("influx").(*ConflictError); !ok {
+		t.Error("Expected a *ConflictError for a key added beneath a deleted object")
+	}
+
+	if _, ok := target.Set("influx.password", "other").(*ConflictError); !ok {
+		t.Error("Expected a *ConflictError for a key added by someone else")
+	}
+
+	// Unrelated keys can still be written
+	if err := target.Set("loggingLevel", "debug"); err != nil {
+		t.Errorf("Set returned error %s", err.Error())
+	}
+}
+
+func TestSetRejected(t *testing.T) {
+	target, _, _ := newWriteBackConfig(t, false)
+	if err := target.SetSchema(`{"properties": {"responseLimit": {"type": "integer"}}}`); err != nil {
+		t.Fatalf("SetSchema returned error %s", err.Error())
+	}
+
+	if _, ok := target.Set("responseLimit", "lots").(*ValidationError); !ok {
+		t.Error("Expected a *ValidationError for an invalid value")
+	}
+
+	local := &Configuration{}
+	if err := local.Set("port", "8085"); err == nil {
+		t.Error("Expected an error when not read from Consul")
+	}
+}
+
+func TestSetFromCallback(t *testing.T) {
+	target, _, _ := newWriteBackConfig(t, false)
+
+	var notified []string
+	target.SetConfigChangeCallback(func(changes []ChangeDetails) {
+		for _, change := range changes {
+			notified = append(notified, change.Name)
+		}
+	})
+
+	var setErr error
+	target.Subscribe("port", func(oldValue interface{}, newValue interface{}) {
+		// A change to the port moves the debug endpoint with it
+		setErr = target.Set("inventory-service.debugEndpoint", "/debug-"+newValue.(string))
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- target.Set("port", "8085")
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Set returned error %s", err.Error())
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for Set called from a subscription")
+	}
+
+	if setErr != nil {
+		t.Errorf("Set from the subscription returned error %s", setErr.Error())
+	}
+
+	if actual, _ := target.GetString("debugEndpoint"); actual != "/debug-8085" {
+		t.Errorf("debugEndpoint not as expected. Expected='/debug-8085', Actual='%s'", actual)
+	}
+
+	// The change made by the subscription is notified after the change it handled
+	expected := []string{"port", "inventory-service.debugEndpoint"}
+	if !reflect.DeepEqual(expected, notified) {
+		t.Errorf("Notifications not as expected. Expected='%v', Actual='%v'", expected, notified)
+	}
+}
+
+func TestSetFromChangeHandler(t *testing.T) {
+	target, _, _ := newWriteBackConfig(t, false)
+
+	var setErr error
+	target.AddChangeHandler(func(changes []ChangeDetails) error {
+		setErr = target.Set("debugEndpoint", "/debug-2")
+		return nil
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- target.Set("port", "8085")
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Set returned error %s", err.Error())
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("Timed out waiting for Set called from a change handler")
+	}
+
+	if setErr == nil {
+		t.Error("Expected an error for Set called from a change handler")
+	}
+}
@@ -8,11 +8,13 @@ package consulApi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -34,6 +36,30 @@ func NewClient(config *Config) (*Client, error) {
 type QueryOptions struct {
 	WaitIndex uint64
 	WaitTime  time.Duration
+
+	ctx context.Context
+}
+
+// WithContext returns a copy of the options with ctx, which cancels a query made with them, such as a
+// blocking query waiting for a change
+func (queryOptions *QueryOptions) WithContext(ctx context.Context) *QueryOptions {
+	withContext := *queryOptions
+	withContext.ctx = ctx
+	return &withContext
+}
+
+// Context returns the context set by WithContext, or context.Background() when none is set
+func (queryOptions *QueryOptions) Context() context.Context {
+	if queryOptions == nil || queryOptions.ctx == nil {
+		return context.Background()
+	}
+	return queryOptions.ctx
+}
+
+// QueryMeta holds the metadata Consul returns with a query
+type QueryMeta struct {
+	// LastIndex is the X-Consul-Index of the result, which is used as the WaitIndex of the next blocking query
+	LastIndex uint64
 }
 
 type KeyValuePairs []*KeyValuePair
@@ -61,6 +87,7 @@ func (client *Client) GetValue(key string, queryOptions *QueryOptions) (*KeyValu
 	if err != nil {
 		return nil, fmt.Errorf("unable to create GET http.NewRquest to %s: %s", endpoint, err.Error())
 	}
+	request = request.WithContext(queryOptions.Context())
 	request.Header.Set("content-type", "application/json;charset=utf-8")
 
 	if queryOptions != nil {
@@ -115,6 +142,75 @@ func (client *Client) GetValue(key string, queryOptions *QueryOptions) (*KeyValu
 	return nil, fmt.Errorf("unable to get value for %s: Received %d status", key, response.StatusCode)
 }
 
+// ListValues returns every key beneath prefix, sorted by key, using a recursive query. A blocking query
+// returns when any key beneath the prefix changes or the wait time expires.
+func (client *Client) ListValues(prefix string, queryOptions *QueryOptions) (KeyValuePairs, *QueryMeta, error) {
+	endpoint := client.buildEndPoint(prefix)
+
+	httpClient := &http.Client{
+		Timeout: time.Second * 1800,
+	}
+
+	request, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create GET http.NewRquest to %s: %s", endpoint, err.Error())
+	}
+	request = request.WithContext(queryOptions.Context())
+	request.Header.Set("content-type", "application/json;charset=utf-8")
+
+	query := request.URL.Query()
+	query.Add("recurse", "")
+	if queryOptions != nil {
+		if queryOptions.WaitIndex != 0 {
+			query.Add("index", strconv.FormatUint(queryOptions.WaitIndex, 10))
+		}
+
+		if queryOptions.WaitTime != 0 {
+			query.Add("wait", durToMsec(queryOptions.WaitTime))
+		}
+	}
+	request.URL.RawQuery = query.Encode()
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to list values for %s: %s", prefix, err.Error())
+	}
+
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	queryMeta := &QueryMeta{}
+	if index := response.Header.Get("X-Consul-Index"); index != "" {
+		queryMeta.LastIndex, err = strconv.ParseUint(index, 10, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to parse X-Consul-Index '%s' for %s: %s", index, prefix, err.Error())
+		}
+	}
+
+	if response.StatusCode == http.StatusNotFound {
+		return KeyValuePairs{}, queryMeta, nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unable to list values for %s: Received %d status", prefix, response.StatusCode)
+	}
+
+	responseData, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading response body for %s: %s", prefix, err.Error())
+	}
+
+	keyValuePairs := KeyValuePairs{}
+	if err := json.Unmarshal(responseData, &keyValuePairs); err != nil {
+		return nil, nil, fmt.Errorf("unable to unmarshal response for prefix %s into a KeyValuePairs struct: %s", prefix, err.Error())
+	}
+
+	return keyValuePairs, queryMeta, nil
+}
+
 func (client *Client) PutValue(key string, value string) error {
 	endpoint := client.buildEndPoint(key)
 
@@ -146,8 +242,77 @@ func (client *Client) PutValue(key string, value string) error {
 	return fmt.Errorf("unable to put value for %s: Received %d status", key, response.StatusCode)
 }
 
+// PutValueCAS sets the value of the key only if its ModifyIndex is still modifyIndex, which is a check-and-set.
+// A modifyIndex of 0 only sets the key if it does not exist yet. It returns false when the key was changed.
+func (client *Client) PutValueCAS(key string, value string, modifyIndex uint64) (bool, error) {
+	query := url.Values{}
+	query.Set("cas", strconv.FormatUint(modifyIndex, 10))
+
+	return client.writeValue("PUT", key, []byte(value), query)
+}
+
 func (client *Client) DeleteValue(key string) error {
-	return nil
+	_, err := client.writeValue("DELETE", key, nil, nil)
+	return err
+}
+
+// DeleteValueCAS deletes the key only if its ModifyIndex is still modifyIndex. It returns false when the key
+// was changed.
+func (client *Client) DeleteValueCAS(key string, modifyIndex uint64) (bool, error) {
+	query := url.Values{}
+	query.Set("cas", strconv.FormatUint(modifyIndex, 10))
+
+	return client.writeValue("DELETE", key, nil, query)
+}
+
+// DeleteTree deletes every key beneath prefix
+func (client *Client) DeleteTree(prefix string) error {
+	query := url.Values{}
+	query.Set("recurse", "")
+
+	_, err := client.writeValue("DELETE", prefix, nil, query)
+	return err
+}
+
+// writeValue sends a PUT or DELETE for the key and returns the result Consul responds with, which is
+// false when a check-and-set fails
+func (client *Client) writeValue(method string, key string, body []byte, query url.Values) (bool, error) {
+	endpoint := client.buildEndPoint(key)
+
+	httpClient := &http.Client{
+		Timeout: time.Second * 1800,
+	}
+
+	request, err := http.NewRequest(method, endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return false, fmt.Errorf("unable to create %s http.NewRquest to %s: %s", method, endpoint, err.Error())
+	}
+	request.Header.Set("content-type", "application/json;charset=utf-8")
+	if query != nil {
+		request.URL.RawQuery = query.Encode()
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return false, fmt.Errorf("unable to %s value for %s: %s", method, key, err.Error())
+	}
+
+	defer func() {
+		if err := response.Body.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unable to %s value for %s: Received %d status", method, key, response.StatusCode)
+	}
+
+	responseData, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return false, fmt.Errorf("error reading response body for %s: %s", key, err.Error())
+	}
+
+	return strings.TrimSpace(string(responseData)) != "false", nil
 }
 
 func durToMsec(dur time.Duration) string {
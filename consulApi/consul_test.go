@@ -7,6 +7,7 @@
 package consulApi
 
 import (
+	"context"
 	"net/http/httptest"
 	"os"
 	"testing"
@@ -178,3 +179,192 @@ func TestBlockingGetValueNotTimedOut(t *testing.T) {
 		t.Fatalf("Actual value received '%s' is not as expected '%s'", actual, expected)
 	}
 }
+
+func TestListValues(t *testing.T) {
+	prefix := "listTest/"
+
+	target, err := NewClient(&Config{Address: consulUrl})
+	if err != nil {
+		t.Fatalf("failed to create NewClient: %s", err.Error())
+	}
+
+	keyValuePairs, queryMeta, err := target.ListValues(prefix, nil)
+	if err != nil {
+		t.Fatalf("failed ListValues for prefix %s: %s", prefix, err.Error())
+	}
+	if len(keyValuePairs) != 0 {
+		t.Fatalf("expected no keys beneath %s, got %d", prefix, len(keyValuePairs))
+	}
+
+	for _, key := range []string{"listTest/b", "listTest/a/c", "otherTest/a"} {
+		if err := target.PutValue(key, "value of "+key); err != nil {
+			t.Fatalf("failed PutValue to %s key: %s", key, err.Error())
+		}
+	}
+
+	keyValuePairs, queryMeta, err = target.ListValues(prefix, nil)
+	if err != nil {
+		t.Fatalf("failed ListValues for prefix %s: %s", prefix, err.Error())
+	}
+
+	if len(keyValuePairs) != 2 || keyValuePairs[0].Key != "listTest/a/c" || keyValuePairs[1].Key != "listTest/b" {
+		t.Fatalf("keys beneath %s not as expected: %v", prefix, keyValuePairs)
+	}
+
+	if string(keyValuePairs[1].Value) != "value of listTest/b" {
+		t.Errorf("value not as expected: %s", keyValuePairs[1].Value)
+	}
+
+	if queryMeta.LastIndex < keyValuePairs[0].ModifyIndex || queryMeta.LastIndex < keyValuePairs[1].ModifyIndex {
+		t.Errorf("LastIndex %d is before the keys were modified", queryMeta.LastIndex)
+	}
+}
+
+func TestBlockingListValuesNotTimedOut(t *testing.T) {
+	prefix := "blockingListTest/"
+	waitTime := time.Second * 5
+
+	target, err := NewClient(&Config{Address: consulUrl})
+	if err != nil {
+		t.Fatalf("failed to create NewClient: %s", err.Error())
+	}
+
+	if err = target.PutValue(prefix+"first", "1"); err != nil {
+		t.Fatalf("failed PutValue: %s", err.Error())
+	}
+
+	_, queryMeta, err := target.ListValues(prefix, nil)
+	if err != nil {
+		t.Fatalf("failed ListValues for prefix %s: %s", prefix, err.Error())
+	}
+
+	go func(t *testing.T) {
+		time.Sleep(time.Second * 1)
+
+		// Adding a key beneath the prefix triggers ListValues to return before timeout
+		if err := target.PutValue(prefix+"second", "2"); err != nil {
+			t.Errorf("failed PutValue: %s", err.Error())
+		}
+	}(t)
+
+	startTime := time.Now()
+	keyValuePairs, _, err := target.ListValues(prefix, &QueryOptions{WaitIndex: queryMeta.LastIndex, WaitTime: waitTime})
+	if err != nil {
+		t.Fatalf("failed ListValues with wait for prefix %s: %s", prefix, err.Error())
+	}
+
+	if actualWaited := time.Since(startTime); actualWaited >= waitTime {
+		t.Fatalf("Didn't wait as expected. Actual %v, Expected %v", actualWaited, waitTime)
+	}
+
+	if len(keyValuePairs) != 2 {
+		t.Errorf("expected 2 keys beneath %s, got %d", prefix, len(keyValuePairs))
+	}
+}
+
+func TestPutValueCAS(t *testing.T) {
+	key := "casKey"
+
+	target, err := NewClient(&Config{Address: consulUrl})
+	if err != nil {
+		t.Fatalf("failed to create NewClient: %s", err.Error())
+	}
+
+	if ok, err := target.PutValueCAS(key, "first", 0); err != nil || !ok {
+		t.Fatalf("expected PutValueCAS of new key to succeed: %v, %v", ok, err)
+	}
+
+	if ok, err := target.PutValueCAS(key, "again", 0); err != nil || ok {
+		t.Fatalf("expected PutValueCAS with index 0 of existing key to fail: %v, %v", ok, err)
+	}
+
+	keyValuePair, err := target.GetValue(key, nil)
+	if err != nil {
+		t.Fatalf("failed GetValue for key %s: %s", key, err.Error())
+	}
+
+	if ok, err := target.PutValueCAS(key, "second", keyValuePair.ModifyIndex); err != nil || !ok {
+		t.Fatalf("expected PutValueCAS with current index to succeed: %v, %v", ok, err)
+	}
+
+	if ok, err := target.PutValueCAS(key, "stale", keyValuePair.ModifyIndex); err != nil || ok {
+		t.Fatalf("expected PutValueCAS with stale index to fail: %v, %v", ok, err)
+	}
+
+	keyValuePair, err = target.GetValue(key, nil)
+	if err != nil || keyValuePair == nil || string(keyValuePair.Value) != "second" {
+		t.Fatalf("value not as expected after check-and-set: %v, %v", keyValuePair, err)
+	}
+
+	if ok, err := target.DeleteValueCAS(key, keyValuePair.ModifyIndex-1); err != nil || ok {
+		t.Fatalf("expected DeleteValueCAS with stale index to fail: %v, %v", ok, err)
+	}
+
+	if ok, err := target.DeleteValueCAS(key, keyValuePair.ModifyIndex); err != nil || !ok {
+		t.Fatalf("expected DeleteValueCAS with current index to succeed: %v, %v", ok, err)
+	}
+
+	if keyValuePair, err = target.GetValue(key, nil); err != nil || keyValuePair != nil {
+		t.Fatalf("expected key to be deleted: %v, %v", keyValuePair, err)
+	}
+}
+
+func TestDeleteTree(t *testing.T) {
+	prefix := "deleteTreeTest/"
+
+	target, err := NewClient(&Config{Address: consulUrl})
+	if err != nil {
+		t.Fatalf("failed to create NewClient: %s", err.Error())
+	}
+
+	for _, key := range []string{prefix + "a", prefix + "b/c"} {
+		if err := target.PutValue(key, "value"); err != nil {
+			t.Fatalf("failed PutValue to %s key: %s", key, err.Error())
+		}
+	}
+
+	if err := target.DeleteTree(prefix); err != nil {
+		t.Fatalf("failed DeleteTree for %s: %s", prefix, err.Error())
+	}
+
+	keyValuePairs, _, err := target.ListValues(prefix, nil)
+	if err != nil || len(keyValuePairs) != 0 {
+		t.Fatalf("expected no keys beneath %s: %v, %v", prefix, keyValuePairs, err)
+	}
+}
+
+func TestBlockingGetValueCanceled(t *testing.T) {
+	key := "cancelTest"
+	waitTime := time.Second * 5
+
+	target, err := NewClient(&Config{Address: consulUrl})
+	if err != nil {
+		t.Fatalf("failed to create NewClient: %s", err.Error())
+	}
+
+	if err = target.PutValue(key, "1"); err != nil {
+		t.Fatalf("failed PutValue: %s", err.Error())
+	}
+
+	keyValuePair, err := target.GetValue(key, nil)
+	if err != nil {
+		t.Fatalf("failed GetValue for key %s: %s", key, err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Second, cancel)
+
+	startTime := time.Now()
+	queryOptions := (&QueryOptions{WaitIndex: keyValuePair.ModifyIndex, WaitTime: waitTime}).WithContext(ctx)
+	if _, err := target.GetValue(key, queryOptions); err == nil {
+		t.Error("expected an error for a canceled GetValue")
+	}
+
+	if _, _, err := target.ListValues(key, queryOptions); err == nil {
+		t.Error("expected an error for a canceled ListValues")
+	}
+
+	if actualWaited := time.Since(startTime); actualWaited >= waitTime {
+		t.Fatalf("Didn't stop waiting when canceled. Actual %v, Expected less than %v", actualWaited, waitTime)
+	}
+}
@@ -12,25 +12,33 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 type MockConsul struct {
+	mutex         sync.Mutex
 	keyValueStore map[string]KeyValuePair
+	// keyChannels holds a channel per key which is closed by the next PUT to wake up blocking GETs
+	keyChannels map[string]chan bool
+	// treeChannel is closed by the next PUT to any key to wake up blocking recursive GETs
+	treeChannel chan bool
+	// index is the last index given to a change, shared by all keys like the Consul raft index
+	index uint64
 }
 
 func NewMockConsul() *MockConsul {
 	mock := MockConsul{}
 	mock.keyValueStore = make(map[string]KeyValuePair)
+	mock.keyChannels = make(map[string]chan bool)
+	mock.treeChannel = make(chan bool)
 	return &mock
 }
 
-var keyChannels map[string]chan bool
-
 func (mock *MockConsul) Start() *httptest.Server {
-	keyChannels = make(map[string]chan bool)
-
 	testMockServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 		if strings.Contains(request.URL.Path, "/v1/kv/") {
 			key := strings.Replace(request.URL.Path, "/v1/kv/", "", 1)
@@ -42,37 +50,40 @@ func (mock *MockConsul) Start() *httptest.Server {
 					log.Printf("error reading request body: %s", err.Error())
 				}
 
-				keyValuePair, found := mock.keyValueStore[key]
-				if found {
-					keyValuePair.ModifyIndex++
-					keyValuePair.Value = body
-				} else {
-					keyValuePair = KeyValuePair{
-						Key:         key,
-						Value:       body,
-						ModifyIndex: 1,
-						CreateIndex: 1,
-						Flags:       0,
-						LockIndex:   0,
-					}
+				if cas, ok := request.URL.Query()["cas"]; ok {
+					mock.writeResult(writer, mock.putValueCAS(key, body, cas[0]))
+					return
 				}
 
-				mock.keyValueStore[key] = keyValuePair
-
+				mock.putValue(key, body)
 				log.Printf("PUTing new value for %s", key)
-				channel, found := keyChannels[key]
-				if found {
-					channel <- true
+
+			case "DELETE":
+				query := request.URL.Query()
+				if _, recurse := query["recurse"]; recurse {
+					mock.deleteValues(key, "")
+				} else {
+					mock.writeResult(writer, mock.deleteValues(key, query.Get("cas")))
+					return
 				}
 
 			case "GET":
 				// this is what the wait query parameters will look like "index=1&wait=600000ms"
 				query := request.URL.Query()
 				waitTime := query.Get("wait")
+				if _, recurse := query["recurse"]; recurse {
+					mock.listValues(writer, key, query.Get("index"), waitTime, request.Context().Done())
+					return
+				}
+
 				if waitTime != "" {
-					waitForNextPut(key, waitTime)
+					mock.waitForNextPut(key, query.Get("index"), waitTime, request.Context().Done())
 				}
+
+				mock.mutex.Lock()
 				keyValuePair, found := mock.keyValueStore[key]
+				mock.mutex.Unlock()
+
 				pairs := KeyValuePairs{&keyValuePair}
 				if !found {
 					http.NotFound(writer, request)
@@ -93,28 +104,192 @@ func (mock *MockConsul) Start() *httptest.Server {
 	return testMockServer
 }
 
-func waitForNextPut(key string, waitTime string) {
+// putValueCAS puts the value only if the key is still at the index, where 0 means the key must not exist
+func (mock *MockConsul) putValueCAS(key string, body []byte, cas string) bool {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if !mock.matchesIndex(key, cas) {
+		return false
+	}
+
+	mock.storeValue(key, body)
+	return true
+}
+
+// deleteValues deletes the key, or every key beneath it when key is a prefix and cas is empty. A non empty
+// cas only deletes the key if it is still at that index.
+func (mock *MockConsul) deleteValues(key string, cas string) bool {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	if cas != "" && !mock.matchesIndex(key, cas) {
+		return false
+	}
+
+	deleted := false
+	for storedKey := range mock.keyValueStore {
+		if storedKey == key || (cas == "" && strings.HasPrefix(storedKey, key)) {
+			delete(mock.keyValueStore, storedKey)
+			mock.notifyChanged(storedKey)
+			deleted = true
+		}
+	}
+
+	if deleted {
+		mock.index++
+		mock.notifyTreeChanged()
+	}
+
+	return true
+}
+
+func (mock *MockConsul) matchesIndex(key string, cas string) bool {
+	keyValuePair, found := mock.keyValueStore[key]
+	if cas == "0" {
+		return !found
+	}
+
+	return found && cas == strconv.FormatUint(keyValuePair.ModifyIndex, 10)
+}
+
+func (mock *MockConsul) writeResult(writer http.ResponseWriter, result bool) {
+	writer.Header().Set("Content-Type", "application/json")
+	if _, err := writer.Write([]byte(strconv.FormatBool(result))); err != nil {
+		log.Printf("error writing data response: %s", err.Error())
+	}
+}
+
+func (mock *MockConsul) putValue(key string, body []byte) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+
+	mock.storeValue(key, body)
+}
+
+// storeValue saves the value and wakes up the blocking GETs watching it. Callers must hold mutex.
+func (mock *MockConsul) storeValue(key string, body []byte) {
+
+	mock.index++
+
+	keyValuePair, found := mock.keyValueStore[key]
+	if found {
+		keyValuePair.ModifyIndex = mock.index
+		keyValuePair.Value = body
+	} else {
+		keyValuePair = KeyValuePair{
+			Key:         key,
+			Value:       body,
+			ModifyIndex: mock.index,
+			CreateIndex: mock.index,
+			Flags:       0,
+			LockIndex:   0,
+		}
+	}
+
+	mock.keyValueStore[key] = keyValuePair
+
+	mock.notifyTreeChanged()
+	mock.notifyChanged(key)
+}
+
+func (mock *MockConsul) notifyChanged(key string) {
+	channel, found := mock.keyChannels[key]
+	if found {
+		close(channel)
+		delete(mock.keyChannels, key)
+	}
+}
+
+func (mock *MockConsul) notifyTreeChanged() {
+	close(mock.treeChannel)
+	mock.treeChannel = make(chan bool)
+}
+
+// waitForNextPut blocks like a Consul blocking query. It returns immediately when the key has already
+// moved past the requested index, otherwise it waits for the next PUT, the wait time to expire or the
+// client to cancel the request.
+func (mock *MockConsul) waitForNextPut(key string, index string, waitTime string, canceled <-chan struct{}) {
 	timeout, err := time.ParseDuration(waitTime)
 	if err != nil {
 		log.Printf("Error parsing waitTime %s into a duration: %s", waitTime, err.Error())
 	}
-	channel := make(chan bool)
-	keyChannels[key] = channel
-	timedOut := false
-	go func() {
-		time.Sleep(timeout)
-		timedOut = true
-		if keyChannels[key] != nil {
-			keyChannels[key] <- true
-			log.Printf("Timed out watching for change on %s", key)
-		}
-	}()
+
+	mock.mutex.Lock()
+	keyValuePair, found := mock.keyValueStore[key]
+	if found && index != "" && index != strconv.FormatUint(keyValuePair.ModifyIndex, 10) {
+		mock.mutex.Unlock()
+		return
+	}
+
+	channel, found := mock.keyChannels[key]
+	if !found {
+		channel = make(chan bool)
+		mock.keyChannels[key] = channel
+	}
+	mock.mutex.Unlock()
 
 	log.Printf("Watching for change on %s", key)
-	<-channel
-	close(channel)
-	keyChannels[key] = nil
-	if !timedOut {
+	select {
+	case <-channel:
 		log.Printf("%s changed", key)
+	case <-time.After(timeout):
+		log.Printf("Timed out watching for change on %s", key)
+	case <-canceled:
+		log.Printf("Stopped watching for change on %s", key)
+	}
+}
+
+// listValues responds to a recursive GET with every key beneath the prefix. A blocking query waits for
+// the next PUT to any key when the index has not moved past the requested one.
+func (mock *MockConsul) listValues(writer http.ResponseWriter, prefix string, index string, waitTime string, canceled <-chan struct{}) {
+	mock.mutex.Lock()
+	if waitTime != "" && index == strconv.FormatUint(mock.index, 10) {
+		channel := mock.treeChannel
+		mock.mutex.Unlock()
+
+		timeout, err := time.ParseDuration(waitTime)
+		if err != nil {
+			log.Printf("Error parsing waitTime %s into a duration: %s", waitTime, err.Error())
+		}
+
+		log.Printf("Watching for changes beneath %s", prefix)
+		select {
+		case <-channel:
+		case <-time.After(timeout):
+			log.Printf("Timed out watching for changes beneath %s", prefix)
+		case <-canceled:
+			log.Printf("Stopped watching for changes beneath %s", prefix)
+		}
+
+		mock.mutex.Lock()
+	}
+
+	pairs := KeyValuePairs{}
+	for key, keyValuePair := range mock.keyValueStore {
+		if strings.HasPrefix(key, prefix) {
+			pair := keyValuePair
+			pairs = append(pairs, &pair)
+		}
+	}
+	lastIndex := mock.index
+	mock.mutex.Unlock()
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key < pairs[j].Key
+	})
+
+	writer.Header().Set("X-Consul-Index", strconv.FormatUint(lastIndex, 10))
+	if len(pairs) == 0 {
+		http.Error(writer, "404 page not found", http.StatusNotFound)
+		return
+	}
+
+	jsonData, _ := json.MarshalIndent(&pairs, "", "  ")
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusOK)
+	if _, err := writer.Write(jsonData); err != nil {
+		log.Printf("error writing data response: %s", err.Error())
 	}
 }
@@ -2,4 +2,8 @@ module github.com/intel/rsp-sw-toolkit-im-suite-utilities
 
 go 1.12
 
-require github.com/influxdata/influxdb v0.0.0-20171219185349-4a7361d0317a
+require (
+	github.com/BurntSushi/toml v0.3.1
+	github.com/influxdata/influxdb v0.0.0-20171219185349-4a7361d0317a
+	gopkg.in/yaml.v2 v2.2.8
+)